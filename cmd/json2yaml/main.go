@@ -1,11 +1,15 @@
 package main
 
 import (
+	"compress/gzip"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"runtime"
+	"strings"
 
 	"github.com/itchyny/json2yaml"
 )
@@ -36,12 +40,27 @@ Version: %s (rev: %s/%s)
 
 Synopsis:
   %% %[1]s file ...
+  %% %[1]s -files-from list.txt
 
 Options:
 `, name, version, revision, runtime.Version())
 		fs.PrintDefaults()
 	}
-	var showVersion bool
+	var showVersion, watch, nulSeparated, gzipOutput bool
+	var filesFrom, schemaFile, validateFile, profileName string
+	cli := &cli{w: os.Stdout}
+	fs.BoolVar(&cli.generated, "generated", false, "emit the header comment of generated code")
+	fs.BoolVar(&cli.sourceComment, "source-comment", false, "emit the source file name comment on each document")
+	fs.StringVar(&schemaFile, "schema", "", "emit the descriptions in the JSON Schema file as comments")
+	fs.StringVar(&validateFile, "validate", "", "validate the input against the JSON Schema file")
+	fs.StringVar(&profileName, "profile", "default",
+		"quote strings for the parser (default, core, pyyaml, go-yaml-v2, go-yaml-v3, snakeyaml, psych)")
+	fs.BoolVar(&cli.verify, "verify", false, "verify that the output parses back to the input")
+	fs.BoolVar(&cli.unbuffered, "unbuffered", false, "flush the output on each line")
+	fs.BoolVar(&gzipOutput, "gzip", false, "compress the output with gzip")
+	fs.StringVar(&filesFrom, "files-from", "", "read input file names from the file (- for stdin)")
+	fs.BoolVar(&nulSeparated, "0", false, "file names are separated by NUL characters")
+	fs.BoolVar(&watch, "watch", false, "watch input files and convert on changes")
 	fs.BoolVar(&showVersion, "version", false, "print version")
 	if err := fs.Parse(args); err != nil {
 		if err == flag.ErrHelp {
@@ -53,29 +72,154 @@ Options:
 		fmt.Printf("%s %s (rev: %s/%s)\n", name, version, revision, runtime.Version())
 		return exitCodeOK
 	}
-	if args = fs.Args(); len(args) == 0 {
-		if err := convert("-"); err != nil {
+	args = fs.Args()
+	profile, ok := profiles[profileName]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "%s: unknown profile: %s\n", name, profileName)
+		return exitCodeErr
+	}
+	if cli.verify && profile != json2yaml.DefaultProfile && profile != json2yaml.CoreSchemaProfile {
+		fmt.Fprintf(os.Stderr, "%s: -verify is not supported with -profile %s\n", name, profileName)
+		return exitCodeErr
+	}
+	cli.profile = profile
+	if schemaFile != "" {
+		schema, err := json2yaml.LoadSchema(schemaFile)
+		if err != nil {
 			fmt.Fprintf(os.Stderr, "%s: %s\n", name, err)
-			exitCode = exitCodeErr
+			return exitCodeErr
 		}
-	} else {
-		for i, arg := range args {
-			if i > 0 {
-				fmt.Fprintln(os.Stdout, "---")
-			}
-			if err := convert(arg); err != nil {
+		cli.schema = schema
+	}
+	if validateFile != "" {
+		schema, err := json2yaml.LoadSchema(validateFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", name, err)
+			return exitCodeErr
+		}
+		cli.validate = schema
+	}
+	if filesFrom != "" {
+		names, err := readFileNames(filesFrom, nulSeparated)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", name, err)
+			return exitCodeErr
+		}
+		if args = append(args, names...); len(args) == 0 {
+			return exitCodeOK
+		}
+	}
+	if gzipOutput {
+		gw := gzip.NewWriter(os.Stdout)
+		defer func() {
+			if err := gw.Close(); err != nil {
 				fmt.Fprintf(os.Stderr, "%s: %s\n", name, err)
 				exitCode = exitCodeErr
 			}
+		}()
+		cli.w = gw
+		if cli.unbuffered {
+			cli.w = flushWriter{gw}
+		}
+	}
+	if watch {
+		return cli.watchFiles(args)
+	}
+	return cli.convertFiles(args)
+}
+
+type cli struct {
+	w             io.Writer
+	generated     bool
+	header        string // written by the first conversion
+	sourceComment bool
+	schema        *json2yaml.Schema
+	validate      *json2yaml.Schema
+	verify        bool
+	unbuffered    bool
+	profile       json2yaml.Profile
+}
+
+// flushWriter flushes the compressed data on each write, so that the lines
+// written by the -unbuffered flag are not buffered by the gzip writer.
+type flushWriter struct {
+	*gzip.Writer
+}
+
+func (w flushWriter) Write(p []byte) (int, error) {
+	n, err := w.Writer.Write(p)
+	if err == nil {
+		err = w.Flush()
+	}
+	return n, err
+}
+
+var profiles = map[string]json2yaml.Profile{
+	"default":    json2yaml.DefaultProfile,
+	"core":       json2yaml.CoreSchemaProfile,
+	"pyyaml":     json2yaml.PyYAMLProfile,
+	"go-yaml-v2": json2yaml.GoYAMLV2Profile,
+	"go-yaml-v3": json2yaml.GoYAMLV3Profile,
+	"snakeyaml":  json2yaml.SnakeYAMLProfile,
+	"psych":      json2yaml.PsychProfile,
+}
+
+func (cli *cli) convertFiles(args []string) (exitCode int) {
+	if len(args) == 0 {
+		args = []string{"-"}
+	}
+	if cli.generated {
+		var from string
+		if len(args) == 1 && args[0] != "-" {
+			from = " from " + args[0]
+		}
+		cli.header = fmt.Sprintf("Code generated by %s%s. DO NOT EDIT.", name, from)
+	}
+	for i, arg := range args {
+		if i > 0 {
+			fmt.Fprintln(cli.w, "---")
+		}
+		if err := cli.convert(arg); err != nil {
+			for msg := range strings.SplitSeq(err.Error(), "\n") {
+				fmt.Fprintf(os.Stderr, "%s: %s\n", name, msg)
+			}
+			exitCode = exitCodeErr
 		}
 	}
 	return
 }
 
-func convert(name string) (err error) {
+func readFileNames(path string, nulSeparated bool) ([]string, error) {
+	var bs []byte
+	var err error
+	if path == "-" {
+		bs, err = io.ReadAll(os.Stdin)
+	} else {
+		bs, err = os.ReadFile(filepath.Clean(path))
+	}
+	if err != nil {
+		return nil, err
+	}
+	sep := "\n"
+	if nulSeparated {
+		sep = "\x00"
+	}
+	var names []string
+	for name := range strings.SplitSeq(string(bs), sep) {
+		if !nulSeparated {
+			name = strings.TrimSuffix(name, "\r")
+		}
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+func (cli *cli) convert(name string) (err error) {
 	if name == "-" {
-		if err := json2yaml.Convert(os.Stdout, os.Stdin); err != nil {
-			return fmt.Errorf("<stdin>: %w", err)
+		if err := cli.convertReader(os.Stdin, "<stdin>"); err != nil {
+			return wrapError("<stdin>", err)
 		}
 		return nil
 	}
@@ -88,8 +232,48 @@ func convert(name string) (err error) {
 			err = cerr
 		}
 	}()
-	if err := json2yaml.Convert(os.Stdout, f); err != nil {
-		return fmt.Errorf("%s: %w", name, err)
+	if err := cli.convertReader(f, name); err != nil {
+		return wrapError(name, err)
 	}
 	return nil
 }
+
+func (cli *cli) convertReader(r io.Reader, name string) error {
+	r, err := json2yaml.Decompress(r)
+	if err != nil {
+		return err
+	}
+	opts := []json2yaml.Option{json2yaml.WithProfile(cli.profile)}
+	if cli.header != "" {
+		opts = append(opts, json2yaml.WithHeaderComment(cli.header))
+		cli.header = ""
+	}
+	if cli.sourceComment {
+		opts = append(opts, json2yaml.WithSourceComment(name))
+	}
+	if cli.schema != nil {
+		opts = append(opts, json2yaml.WithSchemaComments(cli.schema))
+	}
+	if cli.validate != nil {
+		opts = append(opts, json2yaml.WithSchemaValidation(cli.validate))
+	}
+	if cli.verify {
+		opts = append(opts, json2yaml.WithVerification())
+	}
+	if cli.unbuffered {
+		opts = append(opts, json2yaml.WithFlushPolicy(json2yaml.FlushByLine))
+	}
+	return json2yaml.Convert(cli.w, r, opts...)
+}
+
+// wrapError prefixes the file name to each of the joined validation errors.
+func wrapError(name string, err error) error {
+	if errs, ok := err.(interface{ Unwrap() []error }); ok {
+		wrapped := make([]error, len(errs.Unwrap()))
+		for i, err := range errs.Unwrap() {
+			wrapped[i] = fmt.Errorf("%s: %w", name, err)
+		}
+		return errors.Join(wrapped...)
+	}
+	return fmt.Errorf("%s: %w", name, err)
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	watchInterval = 500 * time.Millisecond
+	watchDebounce = 200 * time.Millisecond
+)
+
+// watchFiles polls the files and directories, and converts the files when the
+// contents change. Polling does not depend on platform specific notifications,
+// and rapid edits are debounced until the files become stable.
+func (cli *cli) watchFiles(args []string) int {
+	if len(args) == 0 || slices.Contains(args, "-") {
+		fmt.Fprintf(os.Stderr, "%s: cannot watch standard input\n", name)
+		return exitCodeErr
+	}
+	if err := watch(args, watchInterval, watchDebounce, nil, func(names []string) error {
+		cli.convertFiles(names)
+		if f, ok := cli.w.(interface{ Flush() error }); ok {
+			return f.Flush()
+		}
+		return nil
+	}); err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", name, err)
+	}
+	return exitCodeErr
+}
+
+// watch calls convert with the file names on each change of the files, until
+// convert fails or done is closed.
+func watch(args []string, interval, debounce time.Duration,
+	done <-chan struct{}, convert func([]string) error) error {
+	sleep := func(d time.Duration) bool {
+		select {
+		case <-done:
+			return false
+		case <-time.After(d):
+			return true
+		}
+	}
+	var prev []fileState
+	for i := 0; ; i++ {
+		curr := watchSnapshot(args)
+		if i > 0 {
+			if slices.Equal(prev, curr) {
+				if !sleep(interval) {
+					return nil
+				}
+				continue
+			}
+			for {
+				if !sleep(debounce) {
+					return nil
+				}
+				next := watchSnapshot(args)
+				if slices.Equal(curr, next) {
+					break
+				}
+				curr = next
+			}
+		}
+		// skip the conversion when the directories have no files, otherwise
+		// convertFiles reads the standard input
+		if len(curr) == 0 {
+			prev = curr
+			continue
+		}
+		names := make([]string, 0, len(curr))
+		for _, s := range curr {
+			names = append(names, s.name)
+		}
+		if err := convert(names); err != nil {
+			return err
+		}
+		prev = curr
+	}
+}
+
+type fileState struct {
+	name    string
+	size    int64
+	modTime int64
+	err     string
+}
+
+// watchSnapshot returns the states of the files, expanding the directories to
+// the JSON files in them, so that newly created files are also watched.
+// Compressed JSON files are included, as the inputs are decompressed.
+func watchSnapshot(args []string) []fileState {
+	var states []fileState
+	for _, arg := range args {
+		_ = filepath.WalkDir(arg, func(path string, d fs.DirEntry, err error) error {
+			if err == nil && d.IsDir() || path != arg && !isJSONFile(path) {
+				return nil
+			}
+			s := fileState{name: path}
+			if err == nil {
+				var fi fs.FileInfo
+				if fi, err = d.Info(); err == nil {
+					s.size, s.modTime = fi.Size(), fi.ModTime().UnixNano()
+				}
+			}
+			if err != nil {
+				s.err = err.Error()
+			}
+			states = append(states, s)
+			return nil
+		})
+	}
+	return states
+}
+
+func isJSONFile(path string) bool {
+	for _, ext := range []string{".json", ".json.gz", ".json.bz2"} {
+		if strings.HasSuffix(path, ext) {
+			return true
+		}
+	}
+	return false
+}
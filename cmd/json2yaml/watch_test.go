@@ -0,0 +1,132 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	testWatchInterval = 10 * time.Millisecond
+	testWatchDebounce = 100 * time.Millisecond
+)
+
+// startWatch watches the files, and returns the channel of the file names of
+// the conversions.
+func startWatch(t *testing.T, args []string) <-chan []string {
+	t.Helper()
+	ch, done, errc := make(chan []string, 10), make(chan struct{}), make(chan error)
+	go func() {
+		errc <- watch(args, testWatchInterval, testWatchDebounce, done,
+			func(names []string) error {
+				ch <- names
+				return nil
+			})
+	}()
+	t.Cleanup(func() {
+		close(done)
+		if err := <-errc; err != nil {
+			t.Errorf("should not raise an error but got: %s", err)
+		}
+	})
+	return ch
+}
+
+func expectConversion(t *testing.T, ch <-chan []string, want []string) {
+	t.Helper()
+	select {
+	case got := <-ch:
+		if !slices.Equal(got, want) {
+			t.Fatalf("should convert %q but got %q", want, got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("should convert %q but got no conversion", want)
+	}
+}
+
+func expectNoConversion(t *testing.T, ch <-chan []string) {
+	t.Helper()
+	select {
+	case got := <-ch:
+		t.Fatalf("should not convert but got %q", got)
+	case <-time.After(3 * testWatchDebounce):
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	dir := t.TempDir()
+	a, b := filepath.Join(dir, "a.json"), filepath.Join(dir, "b.json")
+	writeFile(t, a, `{"a":1}`)
+	writeFile(t, filepath.Join(dir, "c.txt"), `c`)
+	ch := startWatch(t, []string{dir})
+	expectConversion(t, ch, []string{a})
+	expectNoConversion(t, ch)
+
+	writeFile(t, a, `{"a":12}`)
+	expectConversion(t, ch, []string{a})
+	expectNoConversion(t, ch)
+
+	writeFile(t, filepath.Join(dir, "c.txt"), `cc`)
+	expectNoConversion(t, ch)
+
+	writeFile(t, b, `{"b":2}`)
+	expectConversion(t, ch, []string{a, b})
+
+	if err := os.Remove(a); err != nil {
+		t.Fatal(err)
+	}
+	expectConversion(t, ch, []string{b})
+}
+
+func TestWatchDebounce(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.json")
+	writeFile(t, a, `0`)
+	ch := startWatch(t, []string{a})
+	expectConversion(t, ch, []string{a})
+
+	// the writes continue longer than the debounce interval
+	for i := range 10 {
+		writeFile(t, a, strings.Repeat("1", i+2))
+		time.Sleep(testWatchDebounce / 5)
+	}
+	expectConversion(t, ch, []string{a})
+	expectNoConversion(t, ch)
+}
+
+func TestWatchEmptySnapshot(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.json")
+	ch := startWatch(t, []string{dir})
+	expectNoConversion(t, ch)
+
+	writeFile(t, a, `{"a":1}`)
+	expectConversion(t, ch, []string{a})
+
+	if err := os.Remove(a); err != nil {
+		t.Fatal(err)
+	}
+	expectNoConversion(t, ch)
+}
+
+func TestWatchError(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "a.json"), `{}`)
+	want := errors.New("conversion error")
+	err := watch([]string{dir}, testWatchInterval, testWatchDebounce, nil,
+		func([]string) error { return want })
+	if err != want {
+		t.Fatalf("should raise an error %q but got: %v", want, err)
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"github.com/itchyny/json2yaml/yaml2json"
+)
+
+const name = "yaml2json"
+
+const version = "0.1.5"
+
+var revision = "HEAD"
+
+func main() {
+	os.Exit(run(os.Args[1:]))
+}
+
+const (
+	exitCodeOK = iota
+	exitCodeErr
+)
+
+func run(args []string) (exitCode int) {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	fs.Usage = func() {
+		fs.SetOutput(os.Stdout)
+		fmt.Printf(`%[1]s - convert YAML to JSON
+
+Version: %s (rev: %s/%s)
+
+Synopsis:
+  %% %[1]s file ...
+
+Options:
+`, name, version, revision, runtime.Version())
+		fs.PrintDefaults()
+	}
+	var showVersion bool
+	fs.BoolVar(&showVersion, "version", false, "print version")
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return exitCodeOK
+		}
+		return exitCodeErr
+	}
+	if showVersion {
+		fmt.Printf("%s %s (rev: %s/%s)\n", name, version, revision, runtime.Version())
+		return exitCodeOK
+	}
+	if args = fs.Args(); len(args) == 0 {
+		args = []string{"-"}
+	}
+	for _, arg := range args {
+		if err := convert(arg); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", name, err)
+			exitCode = exitCodeErr
+		}
+	}
+	return
+}
+
+func convert(name string) (err error) {
+	if name == "-" {
+		if err := yaml2json.Convert(os.Stdout, os.Stdin); err != nil {
+			return fmt.Errorf("<stdin>: %w", err)
+		}
+		return nil
+	}
+	f, err := os.Open(filepath.Clean(name))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+	}()
+	if err := yaml2json.Convert(os.Stdout, f); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package json2yaml
+
+import (
+	"io"
+	"sync"
+)
+
+// Converter is a reusable converter from JSON to YAML, which converts many
+// inputs with the same options and writes them as continuous documents.
+type Converter struct {
+	c    converter
+	opts []Option
+}
+
+// NewConverter returns a new converter that writes to w.
+func NewConverter(w io.Writer, opts ...Option) *Converter {
+	cv := &Converter{opts: opts}
+	cv.c.reset(w, opts)
+	return cv
+}
+
+// Convert reads JSON from r and writes YAML. The documents are written
+// continuously from the previous conversion, and the converter is reset on
+// failure of the conversion.
+func (cv *Converter) Convert(r io.Reader) error {
+	err := cv.c.convert(r)
+	if err != nil {
+		cv.c.reset(cv.c.w, cv.opts)
+	}
+	return err
+}
+
+// Reset clears the state of the converter and switches the output to w.
+func (cv *Converter) Reset(w io.Writer) {
+	cv.c.reset(w, cv.opts)
+}
+
+// the converters of large buffers are not reused to avoid retaining memory
+const maxPooledBufferSize = 64 * 1024
+
+var converterPool = sync.Pool{
+	New: func() any { return new(converter) },
+}
+
+func getConverter(w io.Writer, opts []Option) *converter {
+	c := converterPool.Get().(*converter)
+	c.reset(w, opts)
+	return c
+}
+
+func putConverter(c *converter) {
+	if !c.reusable() {
+		return
+	}
+	c.reset(nil, nil)
+	converterPool.Put(c)
+}
+
+func (c *converter) reusable() bool {
+	return c.buf.Cap() <= maxPooledBufferSize
+}
@@ -0,0 +1,26 @@
+package json2yaml
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestConverterReusable(t *testing.T) {
+	c := getConverter(io.Discard, nil)
+	if err := c.convert(strings.NewReader(`["abcdefg"]`)); err != nil {
+		t.Fatalf("should not raise an error but got: %s", err)
+	}
+	if !c.reusable() {
+		t.Fatalf("should be reusable with the buffer of %d bytes", c.buf.Cap())
+	}
+
+	s := strings.Repeat("abcdefg", maxPooledBufferSize/7+1)
+	if err := c.convert(strings.NewReader(`["` + s + `"]`)); err != nil {
+		t.Fatalf("should not raise an error but got: %s", err)
+	}
+	if c.reusable() {
+		t.Fatalf("should not be reusable with the buffer of %d bytes", c.buf.Cap())
+	}
+	putConverter(c)
+}
@@ -0,0 +1,69 @@
+package json2yaml_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/itchyny/json2yaml"
+)
+
+func TestConverter(t *testing.T) {
+	var sb strings.Builder
+	c := json2yaml.NewConverter(&sb, json2yaml.WithHeaderComment("header"))
+	for _, src := range []string{`{"a":1}`, `[2] 3`} {
+		if err := c.Convert(strings.NewReader(src)); err != nil {
+			t.Fatalf("should not raise an error but got: %s", err)
+		}
+	}
+	if got, want := sb.String(), "# header\na: 1\n---\n- 2\n---\n3\n"; got != want {
+		t.Fatalf("should write\n  %q\nbut got\n  %q", want, got)
+	}
+
+	sb.Reset()
+	c.Reset(&sb)
+	if err := c.Convert(strings.NewReader(`{"a":[`)); err == nil {
+		t.Fatalf("should raise an error but got no error")
+	}
+	if got, want := sb.String(), "# header\na:"; !strings.HasPrefix(got, want) {
+		t.Fatalf("should write\n  %q\nbut got\n  %q", want, got)
+	}
+
+	sb.Reset()
+	if err := c.Convert(strings.NewReader(`{"b":2}`)); err != nil {
+		t.Fatalf("should not raise an error but got: %s", err)
+	}
+	if got, want := sb.String(), "# header\nb: 2\n"; got != want {
+		t.Fatalf("should write\n  %q\nbut got\n  %q", want, got)
+	}
+}
+
+const benchmarkSource = `{"name":"json2yaml","version":"1.0.0","tags":["json","yaml","converter"],` +
+	`"nested":{"number":3.14,"boolean":true,"null":null,"text":"foo\nbar\n"}}`
+
+func BenchmarkConvert(b *testing.B) {
+	b.ReportAllocs()
+	var sb strings.Builder
+	r := strings.NewReader(benchmarkSource)
+	for b.Loop() {
+		sb.Reset()
+		r.Reset(benchmarkSource)
+		if err := json2yaml.Convert(&sb, r); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkConverter(b *testing.B) {
+	b.ReportAllocs()
+	var sb strings.Builder
+	r := strings.NewReader(benchmarkSource)
+	c := json2yaml.NewConverter(&sb)
+	for b.Loop() {
+		sb.Reset()
+		r.Reset(benchmarkSource)
+		c.Reset(&sb)
+		if err := c.Convert(r); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
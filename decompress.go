@@ -0,0 +1,32 @@
+package json2yaml
+
+import (
+	"bufio"
+	"compress/bzip2"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+)
+
+// Decompress returns a reader which transparently decompresses gzip, bzip2 or
+// zlib compressed data detected by the magic bytes. Other data is read as is.
+// Wrap the reader passed to [Convert] to accept compressed inputs.
+func Decompress(r io.Reader) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	bs, err := br.Peek(3)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	switch {
+	case len(bs) >= 2 && bs[0] == 0x1F && bs[1] == 0x8B:
+		return gzip.NewReader(br)
+	case len(bs) >= 3 && string(bs) == "BZh":
+		return bzip2.NewReader(br), nil
+	// compression method deflate, window size up to 32 KiB, no preset
+	// dictionary, and the header checksum; JSON never starts like this
+	case len(bs) >= 2 && bs[0]&0x0F == 8 && bs[0]>>4 <= 7 && bs[1]&0x20 == 0 &&
+		(uint(bs[0])<<8|uint(bs[1]))%31 == 0:
+		return zlib.NewReader(br)
+	}
+	return br, nil
+}
@@ -0,0 +1,108 @@
+package json2yaml_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/itchyny/json2yaml"
+)
+
+func TestDecompress(t *testing.T) {
+	const src = `{"foo": [1, 2]}`
+	testCases := []struct {
+		name string
+		src  func() []byte
+		want string
+		err  string
+	}{
+		{
+			name: "plain",
+			src:  func() []byte { return []byte(src) },
+			want: "foo:\n  - 1\n  - 2\n",
+		},
+		{
+			name: "short",
+			src:  func() []byte { return []byte("80") },
+			want: "80\n",
+		},
+		{
+			name: "empty",
+			src:  func() []byte { return nil },
+			want: "",
+		},
+		{
+			name: "gzip",
+			src: func() []byte {
+				var buf bytes.Buffer
+				w := gzip.NewWriter(&buf)
+				_, _ = w.Write([]byte(src))
+				_ = w.Close()
+				return buf.Bytes()
+			},
+			want: "foo:\n  - 1\n  - 2\n",
+		},
+		{
+			name: "zlib",
+			src: func() []byte {
+				var buf bytes.Buffer
+				w := zlib.NewWriter(&buf)
+				_, _ = w.Write([]byte(src))
+				_ = w.Close()
+				return buf.Bytes()
+			},
+			want: "foo:\n  - 1\n  - 2\n",
+		},
+		{
+			name: "bzip2",
+			src: func() []byte {
+				return []byte("BZh91AY&SY\xf2D\xee\xed\x00\x00\x06\x9b\x80P\x040\x10\x00\n\x01\x00\x80\n \x00\"" +
+					"\x00\xd0\xd0@\xd04\x1e\xb4\x8c\x16\xd8\xe0\xf7x\xbb\x92)\xc2\x84\x87\x92'wh")
+			},
+			want: "foo:\n  - 1\n  - 2\n",
+		},
+		{
+			name: "broken gzip",
+			src:  func() []byte { return []byte("\x1F\x8B\x08") },
+			err:  "unexpected EOF",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r, err := json2yaml.Decompress(bytes.NewReader(tc.src()))
+			if err == nil {
+				var sb strings.Builder
+				if err = json2yaml.Convert(&sb, r); sb.String() != tc.want {
+					t.Fatalf("should write\n  %q\nbut got\n  %q", tc.want, sb.String())
+				}
+			}
+			if tc.err == "" {
+				if err != nil {
+					t.Fatalf("should not raise an error but got: %s", err)
+				}
+			} else {
+				if err == nil {
+					t.Fatalf("should raise an error %q but got no error", tc.err)
+				}
+				if !strings.Contains(err.Error(), tc.err) {
+					t.Fatalf("should raise an error %q but got error %q", tc.err, err)
+				}
+			}
+		})
+	}
+}
+
+type errReader struct{}
+
+func (errReader) Read([]byte) (int, error) {
+	return 0, io.ErrClosedPipe
+}
+
+func TestDecompressError(t *testing.T) {
+	if _, err := json2yaml.Decompress(errReader{}); err != io.ErrClosedPipe {
+		t.Fatalf("should raise an error %q but got error %q", io.ErrClosedPipe, err)
+	}
+}
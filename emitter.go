@@ -0,0 +1,218 @@
+package json2yaml
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ScalarStyle is a style hint of scalars for [Emitter.Scalar].
+type ScalarStyle int
+
+const (
+	// AutoStyle writes the scalar in the style chosen by the converter.
+	AutoStyle ScalarStyle = iota
+	// DoubleQuotedStyle writes the string in double-quoted style.
+	DoubleQuotedStyle
+	// LiteralStyle writes the string in literal block style, unless the
+	// string cannot be represented in the style.
+	LiteralStyle
+)
+
+// Emitter writes YAML by the events of documents, mappings, sequences and
+// scalars, like the tokens of JSON. The events are checked to form
+// well-formed documents, each of which contains exactly one value.
+type Emitter struct {
+	c        *converter
+	checker  tokenChecker
+	started  bool
+	hasValue bool
+	comment  string
+	pending  *emitterEvent
+}
+
+type emitterEvent struct {
+	token   json.Token
+	comment string
+	style   ScalarStyle
+}
+
+// NewEmitter returns a new emitter that writes to w.
+func NewEmitter(w io.Writer, opts ...Option) *Emitter {
+	c := newConverter(w, opts)
+	c.buf.Grow(2 * c.bufferSize)
+	return &Emitter{c: c}
+}
+
+// StartDocument starts a document.
+func (e *Emitter) StartDocument() error {
+	if e.started {
+		return errors.New("document is already started")
+	}
+	if err := e.c.checkOptions(); err != nil {
+		return err
+	}
+	if e.c.header != "" && e.c.documents == 0 {
+		e.c.writeComment(e.c.header)
+	}
+	e.started, e.hasValue = true, false
+	return nil
+}
+
+// EndDocument ends the document, and flushes the output.
+func (e *Emitter) EndDocument() error {
+	if !e.started {
+		return errors.New("document is not started")
+	}
+	if !e.hasValue || len(e.checker.stack) > 0 {
+		return errors.New("document is not completed")
+	}
+	err := e.process(false)
+	e.started = false
+	if ferr := e.c.flush(); ferr != nil && err == nil {
+		err = ferr
+	}
+	if err == nil && e.c.validator != nil {
+		err = errors.Join(e.c.validator.errs...)
+		e.c.validator.errs = nil
+	}
+	return err
+}
+
+// StartMapping starts a mapping.
+func (e *Emitter) StartMapping() error {
+	return e.emit(json.Delim('{'), AutoStyle)
+}
+
+// EndMapping ends the mapping.
+func (e *Emitter) EndMapping() error {
+	return e.emit(json.Delim('}'), AutoStyle)
+}
+
+// StartSequence starts a sequence.
+func (e *Emitter) StartSequence() error {
+	return e.emit(json.Delim('['), AutoStyle)
+}
+
+// EndSequence ends the sequence.
+func (e *Emitter) EndSequence() error {
+	return e.emit(json.Delim(']'), AutoStyle)
+}
+
+// Key writes the key of the mapping.
+func (e *Emitter) Key(key string) error {
+	if !e.expectKey() {
+		return fmt.Errorf("unexpected key %s", strconv.Quote(key))
+	}
+	return e.emit(key, AutoStyle)
+}
+
+// Scalar writes the scalar value, which is nil, bool, string, [json.Number],
+// or a value of the integer or floating-point types. The style hint is used
+// for the strings.
+func (e *Emitter) Scalar(value any, style ScalarStyle) error {
+	var token json.Token
+	switch v := value.(type) {
+	case nil, bool, string, json.Number:
+		token = v
+	case int:
+		token = json.Number(strconv.FormatInt(int64(v), 10))
+	case int8:
+		token = json.Number(strconv.FormatInt(int64(v), 10))
+	case int16:
+		token = json.Number(strconv.FormatInt(int64(v), 10))
+	case int32:
+		token = json.Number(strconv.FormatInt(int64(v), 10))
+	case int64:
+		token = json.Number(strconv.FormatInt(v, 10))
+	case uint:
+		token = json.Number(strconv.FormatUint(uint64(v), 10))
+	case uint8:
+		token = json.Number(strconv.FormatUint(uint64(v), 10))
+	case uint16:
+		token = json.Number(strconv.FormatUint(uint64(v), 10))
+	case uint32:
+		token = json.Number(strconv.FormatUint(uint64(v), 10))
+	case uint64:
+		token = json.Number(strconv.FormatUint(v, 10))
+	case float32:
+		if math.IsInf(float64(v), 0) || math.IsNaN(float64(v)) {
+			return fmt.Errorf("invalid number: %v", v)
+		}
+		token = json.Number(formatFloat(float64(v), 32))
+	case float64:
+		token = v
+	default:
+		return fmt.Errorf("invalid scalar type: %T", value)
+	}
+	if e.expectKey() {
+		return fmt.Errorf("unexpected scalar for object key")
+	}
+	return e.emit(token, style)
+}
+
+// Comment writes the comment lines before the next value of the document, or
+// the next key of the mapping.
+func (e *Emitter) Comment(text string) error {
+	if !e.started || e.hasValue && !e.expectKey() {
+		return errors.New("comment is not allowed here")
+	}
+	if e.comment != "" {
+		text = e.comment + "\n" + text
+	}
+	e.comment = strings.TrimSuffix(text, "\n")
+	return nil
+}
+
+func (e *Emitter) expectKey() bool {
+	n := len(e.checker.stack)
+	return n > 0 && e.checker.stack[n-1] == '{'
+}
+
+func (e *Emitter) emit(token json.Token, style ScalarStyle) error {
+	if !e.started {
+		return errors.New("document is not started")
+	}
+	if e.hasValue && len(e.checker.stack) == 0 {
+		return errors.New("document already has a value")
+	}
+	closing := token == json.Delim('}') || token == json.Delim(']')
+	if e.comment != "" && closing {
+		return errors.New("comment is not followed by a value")
+	}
+	token, err := e.checker.check(token)
+	if err != nil {
+		return err
+	}
+	err = e.process(!closing)
+	e.pending = &emitterEvent{token, e.comment, style}
+	e.comment, e.hasValue = "", true
+	return err
+}
+
+// process converts the pending event, where more reports whether the next
+// event is not the end of mapping or sequence.
+func (e *Emitter) process(more bool) error {
+	if e.pending == nil {
+		return nil
+	}
+	ev := e.pending
+	e.pending = nil
+	e.c.nextComment, e.c.nextStyle = ev.comment, ev.style
+	defer func() { e.c.nextStyle = AutoStyle }()
+	if err := e.c.convertToken(ev.token, emitterMore(more)); err != nil {
+		return err
+	}
+	return e.c.flushToken()
+}
+
+// emitterMore looks ahead the next event for convertToken.
+type emitterMore bool
+
+func (more emitterMore) More() bool {
+	return bool(more)
+}
@@ -0,0 +1,404 @@
+package json2yaml_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/itchyny/json2yaml"
+)
+
+func TestEmitter(t *testing.T) {
+	testCases := []struct {
+		name string
+		emit func(*json2yaml.Emitter) error
+		opts []json2yaml.Option
+		want string
+		err  string
+	}{
+		{
+			name: "mapping",
+			emit: func(e *json2yaml.Emitter) error {
+				return errors.Join(
+					e.StartDocument(),
+					e.StartMapping(),
+					e.Key("a"),
+					e.Scalar(1, json2yaml.AutoStyle),
+					e.Key("b"),
+					e.StartSequence(),
+					e.Scalar(true, json2yaml.AutoStyle),
+					e.Scalar(nil, json2yaml.AutoStyle),
+					e.Scalar(2.5, json2yaml.AutoStyle),
+					e.Scalar(float32(0.1), json2yaml.AutoStyle),
+					e.Scalar(json.Number("1.50"), json2yaml.AutoStyle),
+					e.Scalar("null", json2yaml.AutoStyle),
+					e.EndSequence(),
+					e.Key("c"),
+					e.StartMapping(),
+					e.EndMapping(),
+					e.Key("d"),
+					e.StartSequence(),
+					e.EndSequence(),
+					e.EndMapping(),
+					e.EndDocument(),
+				)
+			},
+			want: `a: 1
+b:
+  - true
+  - null
+  - 2.5
+  - 0.1
+  - 1.50
+  - "null"
+c: {}
+d: []
+`,
+		},
+		{
+			name: "multiple documents",
+			emit: func(e *json2yaml.Emitter) error {
+				return errors.Join(
+					e.StartDocument(),
+					e.Scalar("a", json2yaml.AutoStyle),
+					e.EndDocument(),
+					e.StartDocument(),
+					e.StartSequence(),
+					e.Scalar(uint8(1), json2yaml.AutoStyle),
+					e.EndSequence(),
+					e.EndDocument(),
+				)
+			},
+			opts: []json2yaml.Option{json2yaml.WithHeaderComment("header")},
+			want: "# header\na\n---\n- 1\n",
+		},
+		{
+			name: "numeric types",
+			emit: func(e *json2yaml.Emitter) error {
+				return errors.Join(
+					e.StartDocument(),
+					e.StartSequence(),
+					e.Scalar(int8(-1), json2yaml.AutoStyle),
+					e.Scalar(int16(-2), json2yaml.AutoStyle),
+					e.Scalar(int32(-3), json2yaml.AutoStyle),
+					e.Scalar(int64(math.MinInt64), json2yaml.AutoStyle),
+					e.Scalar(uint(4), json2yaml.AutoStyle),
+					e.Scalar(uint16(5), json2yaml.AutoStyle),
+					e.Scalar(uint32(6), json2yaml.AutoStyle),
+					e.Scalar(uint64(math.MaxUint64), json2yaml.AutoStyle),
+					e.Scalar(float32(1e20), json2yaml.AutoStyle),
+					e.EndSequence(),
+					e.EndDocument(),
+				)
+			},
+			want: `- -1
+- -2
+- -3
+- -9223372036854775808
+- 4
+- 5
+- 6
+- 18446744073709551615
+- 100000000000000000000
+`,
+		},
+		{
+			name: "scalar styles",
+			emit: func(e *json2yaml.Emitter) error {
+				return errors.Join(
+					e.StartDocument(),
+					e.StartMapping(),
+					e.Key("a"),
+					e.Scalar("foo", json2yaml.DoubleQuotedStyle),
+					e.Key("b"),
+					e.Scalar("foo\nbar", json2yaml.DoubleQuotedStyle),
+					e.Key("c"),
+					e.Scalar("foo", json2yaml.LiteralStyle),
+					e.Key("d"),
+					e.Scalar("foo\nbar\n", json2yaml.LiteralStyle),
+					e.Key("e"),
+					e.Scalar(" foo\n", json2yaml.LiteralStyle),
+					e.Key("f"),
+					e.Scalar("foo\nbar", json2yaml.AutoStyle),
+					e.Key("g"),
+					e.Scalar(1, json2yaml.DoubleQuotedStyle),
+					e.EndMapping(),
+					e.EndDocument(),
+				)
+			},
+			want: `a: "foo"
+b: "foo\nbar"
+c: |-
+  foo
+d: |
+  foo
+  bar
+e: " foo\n"
+f: |-
+  foo
+  bar
+g: 1
+`,
+		},
+		{
+			name: "comments",
+			emit: func(e *json2yaml.Emitter) error {
+				return errors.Join(
+					e.StartDocument(),
+					e.Comment("document"),
+					e.StartMapping(),
+					e.Comment("key a\nsecond line\n"),
+					e.Key("a"),
+					e.StartMapping(),
+					e.Comment("key b"),
+					e.Key("b"),
+					e.Scalar(1, json2yaml.AutoStyle),
+					e.EndMapping(),
+					e.Comment("key c"),
+					e.Comment("another"),
+					e.Key("c"),
+					e.Scalar(2, json2yaml.AutoStyle),
+					e.EndMapping(),
+					e.EndDocument(),
+				)
+			},
+			want: `# document
+# key a
+# second line
+a:
+  # key b
+  b: 1
+# key c
+# another
+c: 2
+`,
+		},
+		{
+			name: "document is not started",
+			emit: func(e *json2yaml.Emitter) error {
+				return e.StartMapping()
+			},
+			err: "document is not started",
+		},
+		{
+			name: "end of document is not started",
+			emit: func(e *json2yaml.Emitter) error {
+				return e.EndDocument()
+			},
+			err: "document is not started",
+		},
+		{
+			name: "document is already started",
+			emit: func(e *json2yaml.Emitter) error {
+				return errors.Join(e.StartDocument(), e.StartDocument())
+			},
+			err: "document is already started",
+		},
+		{
+			name: "document already has a value",
+			emit: func(e *json2yaml.Emitter) error {
+				return errors.Join(
+					e.StartDocument(),
+					e.Scalar(1, json2yaml.AutoStyle),
+					e.Scalar(2, json2yaml.AutoStyle),
+				)
+			},
+			err: "document already has a value",
+		},
+		{
+			name: "document is not completed",
+			emit: func(e *json2yaml.Emitter) error {
+				return errors.Join(
+					e.StartDocument(),
+					e.StartSequence(),
+					e.EndDocument(),
+				)
+			},
+			err: "document is not completed",
+		},
+		{
+			name: "empty document",
+			emit: func(e *json2yaml.Emitter) error {
+				return errors.Join(e.StartDocument(), e.EndDocument())
+			},
+			err: "document is not completed",
+		},
+		{
+			name: "unexpected key",
+			emit: func(e *json2yaml.Emitter) error {
+				return errors.Join(
+					e.StartDocument(),
+					e.StartSequence(),
+					e.Key("a"),
+				)
+			},
+			err: `unexpected key "a"`,
+		},
+		{
+			name: "unexpected scalar for object key",
+			emit: func(e *json2yaml.Emitter) error {
+				return errors.Join(
+					e.StartDocument(),
+					e.StartMapping(),
+					e.Scalar("a", json2yaml.AutoStyle),
+				)
+			},
+			err: "unexpected scalar for object key",
+		},
+		{
+			name: "unexpected end of mapping",
+			emit: func(e *json2yaml.Emitter) error {
+				return errors.Join(
+					e.StartDocument(),
+					e.StartMapping(),
+					e.Key("a"),
+					e.EndMapping(),
+				)
+			},
+			err: "unexpected end of object",
+		},
+		{
+			name: "unexpected end of sequence",
+			emit: func(e *json2yaml.Emitter) error {
+				return errors.Join(
+					e.StartDocument(),
+					e.StartMapping(),
+					e.EndSequence(),
+				)
+			},
+			err: "unexpected end of array",
+		},
+		{
+			name: "comment is not allowed",
+			emit: func(e *json2yaml.Emitter) error {
+				return errors.Join(
+					e.StartDocument(),
+					e.StartSequence(),
+					e.Comment("comment"),
+				)
+			},
+			err: "comment is not allowed here",
+		},
+		{
+			name: "comment is not followed by a value",
+			emit: func(e *json2yaml.Emitter) error {
+				return errors.Join(
+					e.StartDocument(),
+					e.StartMapping(),
+					e.Comment("comment"),
+					e.EndMapping(),
+				)
+			},
+			err: "comment is not followed by a value",
+		},
+		{
+			name: "invalid scalar type",
+			emit: func(e *json2yaml.Emitter) error {
+				return errors.Join(
+					e.StartDocument(),
+					e.Scalar([]int{}, json2yaml.AutoStyle),
+				)
+			},
+			err: "invalid scalar type: []int",
+		},
+		{
+			name: "invalid number",
+			emit: func(e *json2yaml.Emitter) error {
+				return errors.Join(
+					e.StartDocument(),
+					e.Scalar(math.NaN(), json2yaml.AutoStyle),
+				)
+			},
+			err: "invalid number: NaN",
+		},
+		{
+			name: "invalid float32 number",
+			emit: func(e *json2yaml.Emitter) error {
+				return errors.Join(
+					e.StartDocument(),
+					e.Scalar(float32(math.Inf(1)), json2yaml.AutoStyle),
+				)
+			},
+			err: "invalid number: +Inf",
+		},
+		{
+			name: "string length limit exceeded",
+			emit: func(e *json2yaml.Emitter) error {
+				return errors.Join(
+					e.StartDocument(),
+					e.StartSequence(),
+					e.Scalar("abc", json2yaml.AutoStyle),
+					e.EndSequence(),
+				)
+			},
+			opts: []json2yaml.Option{json2yaml.WithMaxStringLength(2)},
+			err:  "string length limit exceeded (limit 2)",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var sb strings.Builder
+			err := tc.emit(json2yaml.NewEmitter(&sb, tc.opts...))
+			if tc.err == "" {
+				if err != nil {
+					t.Fatalf("should not raise an error but got: %s", err)
+				}
+				if got, want := diff(sb.String(), tc.want); got != want {
+					t.Fatalf("should write\n  %q\nbut got\n  %q", want, got)
+				}
+			} else {
+				if err == nil {
+					t.Fatalf("should raise an error %q but got no error", tc.err)
+				}
+				if err.Error() != tc.err {
+					t.Fatalf("should raise an error %q but got error %q", tc.err, err)
+				}
+			}
+		})
+	}
+}
+
+func TestEmitterValidation(t *testing.T) {
+	dir := writeSchemaFiles(t, map[string]string{"schema.json": `{"type": "string"}`})
+	s, err := json2yaml.LoadSchema(filepath.Join(dir, "schema.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sb strings.Builder
+	e := json2yaml.NewEmitter(&sb, json2yaml.WithSchemaValidation(s))
+	err = errors.Join(
+		e.StartDocument(),
+		e.Scalar(1, json2yaml.AutoStyle),
+		e.EndDocument(),
+	)
+	if got, want := fmt.Sprint(err), "$: expected string but got number"; got != want {
+		t.Fatalf("should raise an error %q but got error %q", want, got)
+	}
+	err = errors.Join(
+		e.StartDocument(),
+		e.Scalar("a", json2yaml.AutoStyle),
+		e.EndDocument(),
+	)
+	if err != nil {
+		t.Fatalf("should not raise an error but got: %s", err)
+	}
+	if got, want := sb.String(), "1\n---\na\n"; got != want {
+		t.Fatalf("should write\n  %q\nbut got\n  %q", want, got)
+	}
+}
+
+func TestEmitterWriteError(t *testing.T) {
+	e := json2yaml.NewEmitter(errWriter{})
+	err := errors.Join(
+		e.StartDocument(),
+		e.Scalar(1, json2yaml.AutoStyle),
+		e.EndDocument(),
+	)
+	if got, want := fmt.Sprint(err), "2"; got != want {
+		t.Fatalf("should raise an error %q but got error %q", want, got)
+	}
+}
@@ -0,0 +1,270 @@
+package json2yaml
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// EncodingError is returned when the input contains an invalid byte sequence
+// in the detected encoding.
+type EncodingError struct {
+	Encoding string
+	Offset   int64
+}
+
+func (err *EncodingError) Error() string {
+	return fmt.Sprintf("invalid %s sequence at byte offset %d", err.Encoding, err.Offset)
+}
+
+// decodeReader transcodes UTF-16 and UTF-32 input to UTF-8. The encoding is
+// detected by the byte order mark, or by the pattern of null bytes in the first
+// four bytes (RFC 4627, section 3). The byte order mark of UTF-8 is skipped.
+// The detection reads only the bytes available, unless they are ambiguous.
+type decodeReader struct {
+	r        io.Reader
+	encoding string
+	width    int
+	order    binary.ByteOrder
+	in       []byte
+	out      []byte
+	offset   int64
+	err      error
+}
+
+func newDecodeReader(r io.Reader) *decodeReader {
+	return &decodeReader{r: r}
+}
+
+func (r *decodeReader) Read(p []byte) (int, error) {
+	if r.encoding == "" {
+		r.detect()
+	}
+	if r.width == 0 {
+		if len(r.in) > 0 {
+			n := copy(p, r.in)
+			r.in = r.in[n:]
+			return n, nil
+		}
+		if r.err != nil {
+			return 0, r.err
+		}
+		return r.r.Read(p)
+	}
+	for len(r.out) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+		r.fill()
+		r.transcode()
+	}
+	n := copy(p, r.out)
+	if n == len(r.out) {
+		r.out = r.out[:0]
+	} else {
+		r.out = r.out[n:]
+	}
+	return n, nil
+}
+
+func (r *decodeReader) detect() {
+	var bs [4]byte
+	var n int
+	for r.err == nil && isAmbiguousEncoding(bs[:n]) {
+		var m int
+		m, r.err = r.r.Read(bs[n:])
+		n += m
+	}
+	r.in = bs[:n]
+	r.encoding = "UTF-8"
+	switch b := string(r.in); {
+	case len(b) >= 3 && b[:3] == "\xEF\xBB\xBF":
+		r.in, r.offset = r.in[3:], 3
+	case len(b) == 4 && b == "\xFF\xFE\x00\x00":
+		r.encoding, r.width, r.order = "UTF-32LE", 4, binary.LittleEndian
+		r.in, r.offset = r.in[4:], 4
+	case len(b) == 4 && b == "\x00\x00\xFE\xFF":
+		r.encoding, r.width, r.order = "UTF-32BE", 4, binary.BigEndian
+		r.in, r.offset = r.in[4:], 4
+	case len(b) >= 2 && b[:2] == "\xFF\xFE":
+		r.encoding, r.width, r.order = "UTF-16LE", 2, binary.LittleEndian
+		r.in, r.offset = r.in[2:], 2
+	case len(b) >= 2 && b[:2] == "\xFE\xFF":
+		r.encoding, r.width, r.order = "UTF-16BE", 2, binary.BigEndian
+		r.in, r.offset = r.in[2:], 2
+	case len(b) == 4 && b[:3] == "\x00\x00\x00" && b[3] != 0:
+		r.encoding, r.width, r.order = "UTF-32BE", 4, binary.BigEndian
+	case len(b) == 4 && b[0] != 0 && b[1:] == "\x00\x00\x00":
+		r.encoding, r.width, r.order = "UTF-32LE", 4, binary.LittleEndian
+	case len(b) >= 2 && b[0] == 0 && b[1] != 0:
+		r.encoding, r.width, r.order = "UTF-16BE", 2, binary.BigEndian
+	case len(b) >= 2 && b[0] != 0 && b[1] == 0:
+		r.encoding, r.width, r.order = "UTF-16LE", 2, binary.LittleEndian
+	}
+	if r.width > 0 {
+		r.in = append(make([]byte, 0, 4*1024), r.in...)
+		r.transcode()
+	}
+}
+
+// isAmbiguousEncoding reports whether the encoding of the input cannot be
+// determined by the first bytes. The input starting with an ASCII character
+// followed by a non-null byte is UTF-8.
+func isAmbiguousEncoding(b []byte) bool {
+	switch {
+	case len(b) >= 4:
+		return false
+	case len(b) == 0 || b[0] == 0 || b[0] >= utf8.RuneSelf:
+		return true
+	default:
+		return len(b) == 1 || b[1] == 0
+	}
+}
+
+func (r *decodeReader) fill() {
+	n, err := r.r.Read(r.in[len(r.in):cap(r.in)])
+	r.in = r.in[:len(r.in)+n]
+	if err != nil {
+		r.err = err
+	}
+}
+
+func (r *decodeReader) transcode() {
+	var i int
+	for ; i+r.width <= len(r.in); i += r.width {
+		var c rune
+		if r.width == 2 {
+			c = rune(r.order.Uint16(r.in[i:]))
+			if utf16.IsSurrogate(c) {
+				if i+4 > len(r.in) && r.err == nil && c < 0xDC00 {
+					break // wait for the low surrogate
+				}
+				if c < 0xDC00 && i+4 <= len(r.in) {
+					c = utf16.DecodeRune(c, rune(r.order.Uint16(r.in[i+2:])))
+				} else {
+					c = utf8.RuneError
+				}
+				if c == utf8.RuneError {
+					r.fail(i)
+					break
+				}
+				i += 2
+			}
+		} else {
+			if c = rune(r.order.Uint32(r.in[i:])); !utf8.ValidRune(c) {
+				r.fail(i)
+				break
+			}
+		}
+		r.out = utf8.AppendRune(r.out, c)
+	}
+	if r.err == io.EOF && i < len(r.in) {
+		r.fail(i) // truncated sequence at the end of input
+	}
+	r.offset += int64(i)
+	r.in = r.in[:copy(r.in, r.in[i:])]
+}
+
+// fail sets the encoding error at the index of the input, unless reading the
+// input failed with an error other than io.EOF.
+func (r *decodeReader) fail(i int) {
+	if r.err == nil || r.err == io.EOF {
+		r.err = &EncodingError{r.encoding, r.offset + int64(i)}
+	}
+}
+
+// rawReader records the input to reread the raw bytes of string tokens, since
+// encoding/json replaces invalid UTF-8 sequences in strings with U+FFFD.
+type rawReader struct {
+	r      *decodeReader
+	buf    []byte
+	start  int
+	offset int64 // input offset of buf[start] in the decoder
+}
+
+func (r *rawReader) Read(p []byte) (int, error) {
+	if r.start > 0 && r.start >= len(r.buf)/2 {
+		r.buf = r.buf[:copy(r.buf, r.buf[r.start:])]
+		r.start = 0
+	}
+	n, err := r.r.Read(p)
+	r.buf = append(r.buf, p[:n]...)
+	return n, err
+}
+
+// token discards the raw bytes until the offset of the end of the token, and
+// rereads the string token preserving invalid bytes if it contains U+FFFD.
+func (r *rawReader) token(token json.Token, offset int64, reject bool) (json.Token, error) {
+	raw, base := r.buf[r.start:r.start+int(offset-r.offset)], r.offset
+	r.start, r.offset = r.start+len(raw), offset
+	if s, ok := token.(string); !ok || !strings.ContainsRune(s, utf8.RuneError) {
+		return token, nil
+	}
+	i := bytes.IndexByte(raw, '"') + 1
+	raw, base = raw[i:len(raw)-1], base+int64(i)
+	bs := make([]byte, 0, len(raw))
+	for i := 0; i < len(raw); {
+		if b := raw[i]; b == '\\' {
+			switch b = raw[i+1]; b {
+			case 'b':
+				bs = append(bs, '\b')
+			case 'f':
+				bs = append(bs, '\f')
+			case 'n':
+				bs = append(bs, '\n')
+			case 'r':
+				bs = append(bs, '\r')
+			case 't':
+				bs = append(bs, '\t')
+			case 'u':
+				c := parseHex4(raw[i+2:])
+				if i += 6; utf16.IsSurrogate(c) {
+					c2 := rune(-1)
+					if i+6 <= len(raw) && raw[i] == '\\' && raw[i+1] == 'u' {
+						c2 = parseHex4(raw[i+2:])
+					}
+					if c = utf16.DecodeRune(c, c2); c != utf8.RuneError {
+						i += 6
+					}
+				}
+				bs = utf8.AppendRune(bs, c)
+				continue
+			default:
+				bs = append(bs, b)
+			}
+			i += 2
+		} else if b < utf8.RuneSelf {
+			bs = append(bs, b)
+			i++
+		} else {
+			c, size := utf8.DecodeRune(raw[i:])
+			if c == utf8.RuneError && size == 1 && reject {
+				// add the length of the byte order mark skipped
+				return nil, &EncodingError{"UTF-8", r.r.offset + base + int64(i)}
+			}
+			bs = append(bs, raw[i:i+size]...)
+			i += size
+		}
+	}
+	return string(bs), nil
+}
+
+func parseHex4(bs []byte) (r rune) {
+	for _, b := range bs[:4] {
+		switch {
+		case '0' <= b && b <= '9':
+			b -= '0'
+		case 'a' <= b && b <= 'f':
+			b -= 'a' - 10
+		default:
+			b -= 'A' - 10
+		}
+		r = r<<4 | rune(b)
+	}
+	return
+}
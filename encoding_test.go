@@ -0,0 +1,274 @@
+package json2yaml_test
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+	"unicode/utf16"
+
+	"github.com/itchyny/json2yaml"
+)
+
+func TestConvertEncoding(t *testing.T) {
+	utf16le := func(s string) string {
+		var sb strings.Builder
+		for _, c := range utf16.Encode([]rune(s)) {
+			sb.WriteByte(byte(c))
+			sb.WriteByte(byte(c >> 8))
+		}
+		return sb.String()
+	}
+	utf16be := func(s string) string {
+		var sb strings.Builder
+		for _, c := range utf16.Encode([]rune(s)) {
+			sb.WriteByte(byte(c >> 8))
+			sb.WriteByte(byte(c))
+		}
+		return sb.String()
+	}
+	utf32le := func(s string) string {
+		var sb strings.Builder
+		for _, c := range s {
+			sb.WriteString(string([]byte{byte(c), byte(c >> 8), byte(c >> 16), 0}))
+		}
+		return sb.String()
+	}
+	utf32be := func(s string) string {
+		var sb strings.Builder
+		for _, c := range s {
+			sb.WriteString(string([]byte{0, byte(c >> 16), byte(c >> 8), byte(c)}))
+		}
+		return sb.String()
+	}
+	const src = "{\"foo\": [\"bar\", \"éあ\U0001F600\", 1]}"
+	const want = "foo:\n  - bar\n  - éあ\U0001F600\n  - 1\n"
+	testCases := []struct {
+		name string
+		src  string
+		want string
+		err  string
+	}{
+		{
+			name: "UTF-8",
+			src:  src,
+			want: want,
+		},
+		{
+			name: "UTF-8 with BOM",
+			src:  "\uFEFF" + src,
+			want: want,
+		},
+		{
+			name: "UTF-16LE",
+			src:  utf16le(src),
+			want: want,
+		},
+		{
+			name: "UTF-16LE with BOM",
+			src:  utf16le("\uFEFF" + src),
+			want: want,
+		},
+		{
+			name: "UTF-16BE",
+			src:  utf16be(src),
+			want: want,
+		},
+		{
+			name: "UTF-16BE with BOM",
+			src:  utf16be("\uFEFF" + src),
+			want: want,
+		},
+		{
+			name: "UTF-32LE",
+			src:  utf32le(src),
+			want: want,
+		},
+		{
+			name: "UTF-32LE with BOM",
+			src:  utf32le("\uFEFF" + src),
+			want: want,
+		},
+		{
+			name: "UTF-32BE",
+			src:  utf32be(src),
+			want: want,
+		},
+		{
+			name: "UTF-32BE with BOM",
+			src:  utf32be("\uFEFF" + src),
+			want: want,
+		},
+		{
+			name: "short UTF-16LE",
+			src:  utf16le("1"),
+			want: "1\n",
+		},
+		{
+			name: "short UTF-16BE",
+			src:  utf16be("1"),
+			want: "1\n",
+		},
+		{
+			name: "large UTF-16LE",
+			src:  utf16le("[" + strings.Repeat(`"test",`, 999) + `"test"]`),
+			want: strings.Repeat("- test\n", 1000),
+		},
+		{
+			name: "large UTF-32BE",
+			src:  utf32be("[" + strings.Repeat("\"\U0001F600\",", 999) + "\"\U0001F600\"]"),
+			want: strings.Repeat("- \U0001F600\n", 1000),
+		},
+		{
+			name: "unpaired high surrogate in UTF-16LE",
+			src:  utf16le(`["a", "`) + "\x00\xD8" + utf16le(`"]`),
+			want: "- a\n- \n",
+			err:  "invalid UTF-16LE sequence at byte offset 14",
+		},
+		{
+			name: "unpaired low surrogate in UTF-16BE",
+			src:  "\xFE\xFF" + utf16be(`["a", "`) + "\xDC\x00" + utf16be(`"]`),
+			want: "- a\n- \n",
+			err:  "invalid UTF-16BE sequence at byte offset 16",
+		},
+		{
+			name: "high surrogate at the end of UTF-16LE",
+			src:  utf16le(`"a`) + "\x3D\xD8",
+			err:  "invalid UTF-16LE sequence at byte offset 4",
+		},
+		{
+			name: "truncated UTF-16BE",
+			src:  utf16be(`"a`) + "\x00",
+			err:  "invalid UTF-16BE sequence at byte offset 4",
+		},
+		{
+			name: "invalid code point in UTF-32LE",
+			src:  utf32le(`"a`) + "\x00\x00\x11\x00" + utf32le(`"`),
+			err:  "invalid UTF-32LE sequence at byte offset 8",
+		},
+		{
+			name: "surrogate in UTF-32BE",
+			src:  utf32be(`"a`) + "\x00\x00\xD8\x00" + utf32be(`"`),
+			err:  "invalid UTF-32BE sequence at byte offset 8",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var sb strings.Builder
+			err := json2yaml.Convert(&sb, strings.NewReader(tc.src))
+			if got, want := diff(sb.String(), tc.want); got != want {
+				t.Fatalf("should write\n  %q\nbut got\n  %q", want, got)
+			}
+			if tc.err == "" {
+				if err != nil {
+					t.Fatalf("should not raise an error but got: %s", err)
+				}
+			} else {
+				if err == nil {
+					t.Fatalf("should raise an error %q but got no error", tc.err)
+				}
+				if err.Error() != tc.err {
+					t.Fatalf("should raise an error %q but got error %q", tc.err, err)
+				}
+			}
+		})
+	}
+}
+
+func TestConvertInvalidUTF8(t *testing.T) {
+	const src = "{\"a\xFF\": [\"\xC0\xAF\", \"\\ud83d\\ude00 \\ud800 \\\\\\\"\\/\\b\\f\\n\\r\\t\\u00e9 \xFE\"," +
+		" \"\\uFFFD\", \"\uFFFD\", \"\xE3\x81\x82\xE3\x81\"], \"\xF0\x9F\x98\": \"\xFF\\n\"}"
+	testCases := []struct {
+		name   string
+		src    string
+		policy json2yaml.InvalidUTF8Policy
+		want   string
+		err    string
+	}{
+		{
+			name:   "replace",
+			src:    src,
+			policy: json2yaml.ReplaceInvalidUTF8,
+			want: "a\uFFFD:\n  - \uFFFD\uFFFD\n  - \"\U0001F600 \uFFFD \\\\\\\"/\\b\\f\\n\\r\\té \uFFFD\"\n" +
+				"  - \uFFFD\n  - \uFFFD\n  - あ\uFFFD\uFFFD\n\uFFFD\uFFFD\uFFFD: |\n  \uFFFD\n",
+		},
+		{
+			name:   "reject",
+			src:    src,
+			policy: json2yaml.RejectInvalidUTF8,
+			err:    "invalid UTF-8 sequence at byte offset 3",
+		},
+		{
+			name:   "reject in value",
+			src:    "\uFEFF" + "[\"\\ud800\", \"\uFFFD\", \"\\n\xFF\"]",
+			policy: json2yaml.RejectInvalidUTF8,
+			want:   "- \uFFFD\n- \uFFFD\n- \n",
+			err:    "invalid UTF-8 sequence at byte offset 24",
+		},
+		{
+			name:   "preserve",
+			src:    src,
+			policy: json2yaml.PreserveInvalidUTF8,
+			want: "\"a\\xFF\":\n  - \"\\xC0\\xAF\"\n  - \"\U0001F600 \uFFFD \\\\\\\"/\\b\\f\\n\\r\\té \\xFE\"\n" +
+				"  - \uFFFD\n  - \uFFFD\n  - \"あ\\xE3\\x81\"\n\"\\xF0\\x9F\\x98\": \"\\xFF\\n\"\n",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var sb strings.Builder
+			err := json2yaml.Convert(&sb, strings.NewReader(tc.src), json2yaml.WithInvalidUTF8(tc.policy))
+			if got, want := diff(sb.String(), tc.want); got != want {
+				t.Fatalf("should write\n  %q\nbut got\n  %q", want, got)
+			}
+			if tc.err == "" {
+				if err != nil {
+					t.Fatalf("should not raise an error but got: %s", err)
+				}
+			} else {
+				if err == nil {
+					t.Fatalf("should raise an error %q but got no error", tc.err)
+				}
+				if err.Error() != tc.err {
+					t.Fatalf("should raise an error %q but got error %q", tc.err, err)
+				}
+			}
+		})
+	}
+}
+
+func TestConvertEncodingReadError(t *testing.T) {
+	// the high surrogate of UTF-16LE truncated by the read error
+	r := io.MultiReader(strings.NewReader("\xFF\xFE\"\x00\x3D\xD8"),
+		iotest.ErrReader(errors.New("read error")))
+	err := json2yaml.Convert(io.Discard, r)
+	if got, want := err.Error(), "read error"; got != want {
+		t.Fatalf("should raise an error %q but got error %q", want, got)
+	}
+}
+
+type chanWriter chan string
+
+func (w chanWriter) Write(bs []byte) (int, error) {
+	w <- string(bs)
+	return len(bs), nil
+}
+
+func TestConvertEncodingShortInput(t *testing.T) {
+	pr, pw := io.Pipe()
+	defer pw.Close()
+	w := make(chanWriter)
+	go json2yaml.Convert(w, pr, json2yaml.WithFlushPolicy(json2yaml.FlushByLine))
+	if _, err := io.WriteString(pw, "1\n"); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case got := <-w:
+		if want := "1\n"; got != want {
+			t.Fatalf("should write\n  %q\nbut got\n  %q", want, got)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("should write the output before the end of input")
+	}
+}
@@ -0,0 +1,133 @@
+package json2yaml_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/itchyny/json2yaml"
+	"github.com/itchyny/json2yaml/yaml2json"
+)
+
+func FuzzConvert(f *testing.F) {
+	for _, tc := range convertTestCases() {
+		if tc.err == "" {
+			f.Add(tc.src, uint8(0))
+			f.Add(tc.src, uint8(0xFF))
+		}
+	}
+	f.Add(`{"<<": "=", "a": ["- a", "? a", "a: b", " ", "\t", "a \n b"]}`, uint8(0))
+	f.Add(`["0664", "0o17", "0x1F", ".inf", "1e3", 123456789012345678901234567890, 1e400]`, uint8(0x06))
+	f.Fuzz(func(t *testing.T, src string, flags uint8) {
+		want, err := decodeTokens(strings.NewReader(src))
+		if err != nil || hasDuplicateKeys(want) {
+			return // YAML does not allow duplicate mapping keys
+		}
+		var opts []json2yaml.Option
+		for i, opt := range []json2yaml.Option{
+			json2yaml.WithASCII(), json2yaml.WithStringTags(),
+			json2yaml.WithNumberTags(), json2yaml.WithBinaryDetection(),
+			json2yaml.WithDocumentStart(), json2yaml.WithDocumentEnd(),
+			json2yaml.WithProfile(json2yaml.CoreSchemaProfile),
+		} {
+			if flags&(1<<i) != 0 {
+				opts = append(opts, opt)
+			}
+		}
+		var sb strings.Builder
+		if err := json2yaml.Convert(&sb, strings.NewReader(src), opts...); err != nil {
+			t.Fatalf("should not raise an error but got: %s", err)
+		}
+		output := sb.String()
+		blockIndent := -1
+		for line := range strings.Lines(output) {
+			line = strings.TrimSuffix(line, "\n")
+			if strings.HasPrefix(line, "\t") {
+				t.Fatalf("should not write a tab at line start: %q", output)
+			}
+			indent := len(line) - len(strings.TrimLeft(line, " "))
+			if blockIndent >= 0 && (indent > blockIndent || line == "") {
+				continue // the content of block scalars can have trailing spaces
+			}
+			if strings.HasSuffix(line, " ") {
+				t.Fatalf("should not write trailing spaces: %q", output)
+			}
+			blockIndent = -1
+			if blockScalarPattern.MatchString(line) {
+				blockIndent = indent
+			}
+		}
+		var buf bytes.Buffer
+		if err := yaml2json.Convert(&buf, strings.NewReader(output)); err != nil {
+			t.Fatalf("should write a valid YAML but got: %s\n%s", err, output)
+		}
+		got, err := decodeTokens(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(got, want) {
+			t.Fatalf("should parse back to %v but got %v\n%s", want, got, output)
+		}
+	})
+}
+
+var blockScalarPattern = regexp.MustCompile(`^ *(?:- )*(?:[^#]*: )?(?:!!binary )?[|>][-+0-9]*$`)
+
+func decodeTokens(r io.Reader) ([]json.Token, error) {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	var tokens []json.Token
+	var depth int
+	for {
+		token, err := dec.Token()
+		if err != nil {
+			if err == io.EOF {
+				if depth > 0 {
+					return nil, io.ErrUnexpectedEOF
+				}
+				return tokens, nil
+			}
+			return nil, err
+		}
+		switch token {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+		tokens = append(tokens, token)
+	}
+}
+
+func hasDuplicateKeys(tokens []json.Token) bool {
+	var stack []map[string]bool // keys of the objects, nil for the arrays
+	var value bool              // whether the next token is an object value
+	for _, token := range tokens {
+		if len(stack) > 0 && stack[len(stack)-1] != nil && !value {
+			if token == json.Delim('}') {
+				stack = stack[:len(stack)-1]
+				continue
+			}
+			keys := stack[len(stack)-1]
+			if keys[token.(string)] {
+				return true
+			}
+			keys[token.(string)], value = true, true
+			continue
+		}
+		switch token {
+		case json.Delim('{'):
+			stack = append(stack, map[string]bool{})
+		case json.Delim('['):
+			stack = append(stack, nil)
+		case json.Delim(']'):
+			stack = stack[:len(stack)-1]
+		}
+		value = false
+	}
+	return false
+}
@@ -3,36 +3,163 @@ package json2yaml
 
 import (
 	"bytes"
+	"context"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"io"
+	"math"
 	"regexp"
+	"strconv"
 	"strings"
 	"unicode/utf8"
 )
 
 // Convert reads JSON from r and writes YAML to w.
-func Convert(w io.Writer, r io.Reader) error {
-	return (&converter{w, new(bytes.Buffer), []byte{'.'}, 0}).convert(r)
+func Convert(w io.Writer, r io.Reader, opts ...Option) error {
+	c := getConverter(w, opts)
+	defer putConverter(c)
+	return c.convert(r)
+}
+
+// ConvertContext reads JSON from r and writes YAML to w, and stops the
+// conversion when ctx is done. Note that blocking reads from r are not
+// interrupted.
+func ConvertContext(ctx context.Context, w io.Writer, r io.Reader, opts ...Option) error {
+	c := getConverter(w, opts)
+	defer putConverter(c)
+	c.ctx = ctx
+	return c.convert(r)
+}
+
+func newConverter(w io.Writer, opts []Option) *converter {
+	c := &converter{}
+	c.reset(w, opts)
+	return c
+}
+
+// reset clears the state of the converter, reusing the buffer and the stack.
+func (c *converter) reset(w io.Writer, opts []Option) {
+	buf, stack := c.buf, c.stack
+	if buf == nil {
+		buf = new(bytes.Buffer)
+	} else {
+		buf.Reset()
+	}
+	*c = converter{w: w, buf: buf, stack: append(stack[:0], '.'), offset: -1,
+		bufferSize: defaultBufferSize}
+	for _, opt := range opts {
+		opt(c)
+	}
 }
 
 type converter struct {
-	w      io.Writer
-	buf    *bytes.Buffer
-	stack  []byte
-	indent int
+	w            io.Writer
+	buf          *bytes.Buffer
+	stack        []byte
+	indent       int
+	raw          *rawReader
+	invalidUTF8  InvalidUTF8Policy
+	ascii        bool
+	numberFormat NumberFormat
+	stringTags   bool
+	profile      Profile
+	numberTags   bool
+	key          string
+	binaryKeys   *regexp.Regexp
+	binaryDetect bool
+	binarySchema *schemaTracker
+	documents    int
+	docStart     bool
+	docEnd       bool
+	directives   []string
+	header       string
+	source       string
+	comments     *schemaTracker
+	validator    *validator
+	verifier     *verifier
+	nextComment  string
+	nextStyle    ScalarStyle
+	limits       limits
+	offset       int64 // input offset after the last token, or -1
+	flushPolicy  FlushPolicy
+	scanned      int // length of the buffer without newlines by flushLines
+	bufferSize   int
+	ctx          context.Context
 }
 
+const defaultBufferSize = 4 * 1024
+
 func (c *converter) flush() error {
-	_, err := c.w.Write(c.buf.Bytes())
-	c.buf.Reset()
+	return c.flushBytes(c.buf.Len())
+}
+
+// flushLines flushes the output up to the last newline, scanning only the
+// bytes written after the previous call.
+func (c *converter) flushLines() error {
+	bs := c.buf.Bytes()
+	if i := bytes.LastIndexByte(bs[c.scanned:], '\n'); i >= 0 {
+		if err := c.flushBytes(c.scanned + i + 1); err != nil {
+			return err
+		}
+	}
+	c.scanned = c.buf.Len()
+	return nil
+}
+
+// flushBytes flushes the first n bytes of the buffer.
+func (c *converter) flushBytes(n int) error {
+	bs, truncated := c.truncateOutput(c.buf.Bytes()[:n])
+	_, err := c.w.Write(bs)
+	if truncated {
+		c.buf.Reset()
+		if err == nil {
+			err = &LimitError{ErrSizeLimit, c.limits.outputSize, c.limits.written}
+		}
+	} else {
+		c.buf.Next(n)
+	}
+	c.scanned = 0
 	return err
 }
 
 func (c *converter) convert(r io.Reader) error {
-	c.buf.Grow(8 * 1024)
-	dec := json.NewDecoder(r)
+	return c.convertTokens(c.newDecoder(r))
+}
+
+func (c *converter) newDecoder(r io.Reader) *json.Decoder {
+	var rd io.Reader = newDecodeReader(r)
+	if c.invalidUTF8 != ReplaceInvalidUTF8 {
+		c.raw = &rawReader{r: rd.(*decodeReader)}
+		rd = c.raw
+	}
+	if c.limits.stringLength > 0 {
+		rd = &stringLimitReader{r: rd, limit: c.limits.stringLength}
+	}
+	dec := json.NewDecoder(rd)
 	dec.UseNumber()
-	err := c.convertInternal(dec)
+	return dec
+}
+
+func (c *converter) convertTokens(dec TokenReader) error {
+	if err := c.start(); err != nil {
+		return err
+	}
+	return c.finish(c.convertInternal(dec))
+}
+
+func (c *converter) start() error {
+	if err := c.checkOptions(); err != nil {
+		return err
+	}
+	c.buf.Grow(2 * c.bufferSize)
+	if c.header != "" && c.documents == 0 {
+		c.writeComment(c.header)
+	}
+	return nil
+}
+
+func (c *converter) finish(err error) error {
 	if err != nil {
 		if bs := c.buf.Bytes(); len(bs) > 0 && bs[len(bs)-1] != '\n' {
 			c.buf.WriteByte('\n')
@@ -41,83 +168,291 @@ func (c *converter) convert(r io.Reader) error {
 	if ferr := c.flush(); ferr != nil && err == nil {
 		err = ferr
 	}
+	if err == nil && c.validator != nil {
+		err = errors.Join(c.validator.errs...)
+		c.validator.errs = nil
+	}
 	return err
 }
 
-func (c *converter) convertInternal(dec *json.Decoder) error {
+// checkOptions reports an error on the combination of options which cannot
+// work together.
+func (c *converter) checkOptions() error {
+	if c.verifier != nil {
+		switch c.profile {
+		case PyYAMLProfile, GoYAMLV2Profile, GoYAMLV3Profile, SnakeYAMLProfile, PsychProfile:
+			// the verifier reads the output by the core schema of YAML 1.2
+			return errors.New("verification is not supported with the profile other than the default and the core schema")
+		}
+	}
+	return nil
+}
+
+func (c *converter) convertInternal(dec TokenReader) error {
 	for {
-		token, err := dec.Token()
-		if err != nil {
+		if err := c.step(dec); err != nil {
 			if err == io.EOF {
-				if len(c.stack) == 1 {
-					return nil
-				}
-				err = io.ErrUnexpectedEOF
+				return nil
 			}
 			return err
 		}
-		if delim, ok := token.(json.Delim); ok {
-			switch delim {
-			case '{', '[':
-				if len(c.stack) > 1 {
-					c.indent += 2
-				}
-				c.stack = append(c.stack, byte(delim))
-				if dec.More() {
-					if c.stack[len(c.stack)-2] == ':' {
-						c.buf.WriteByte('\n')
-						c.writeIndent()
-					}
-					if c.stack[len(c.stack)-1] == '[' {
-						c.buf.WriteString("- ")
-					}
-				} else {
-					if c.stack[len(c.stack)-2] == ':' {
-						c.buf.WriteByte(' ')
-					}
-					if c.stack[len(c.stack)-1] == '{' {
-						c.buf.WriteString("{}\n")
-					} else {
-						c.buf.WriteString("[]\n")
-					}
+	}
+}
+
+// step reads a token from dec and converts it, and returns io.EOF at the end
+// of the input.
+func (c *converter) step(dec TokenReader) error {
+	if c.ctx != nil {
+		select {
+		case <-c.ctx.Done():
+			return c.ctx.Err()
+		default:
+		}
+	}
+	token, err := dec.Token()
+	if err != nil {
+		if err == io.EOF && len(c.stack) > 1 {
+			err = io.ErrUnexpectedEOF
+		}
+		return err
+	}
+	if d, ok := dec.(*json.Decoder); ok {
+		c.offset = d.InputOffset()
+	}
+	if c.raw != nil {
+		if token, err = c.raw.token(token, c.offset,
+			c.invalidUTF8 == RejectInvalidUTF8); err != nil {
+			return err
+		}
+	}
+	if err := c.convertToken(token, dec); err != nil {
+		return err
+	}
+	return c.flushToken()
+}
+
+// flushToken flushes the output after converting a token, by the flush
+// policy and the output size limit.
+func (c *converter) flushToken() error {
+	if c.flushPolicy == FlushByLine && c.verifier == nil {
+		if err := c.flushLines(); err != nil {
+			return err
+		}
+	}
+	return c.checkOutputSize()
+}
+
+// convertToken converts the token, where dec.More is used to look ahead.
+func (c *converter) convertToken(token json.Token, dec interface{ More() bool }) error {
+	if err := c.checkLimits(token); err != nil {
+		return err
+	}
+	if c.validator != nil {
+		if err := c.validator.token(token); err != nil {
+			return err
+		}
+	}
+	if c.verifier != nil {
+		c.verifyToken(token)
+	}
+	if len(c.stack) == 1 {
+		c.writeDocumentStart()
+		if c.nextComment != "" {
+			c.writeComment(c.nextComment)
+			c.nextComment = ""
+		}
+	}
+	var comment string
+	if parent := c.stack[len(c.stack)-1]; c.comments != nil {
+		c.comments.track(parent, token)
+		if _, ok := token.(string); ok && parent == '{' {
+			comment = c.comments.next.lookup(func(s *schema) string { return s.description })
+		}
+	}
+	if c.binarySchema != nil {
+		c.binarySchema.track(c.stack[len(c.stack)-1], token)
+	}
+	if c.nextComment != "" {
+		comment, c.nextComment = c.nextComment, ""
+	}
+	if delim, ok := token.(json.Delim); ok {
+		switch delim {
+		case '{', '[':
+			if len(c.stack) > 1 {
+				c.indent += 2
+			}
+			c.stack = append(c.stack, byte(delim))
+			if dec.More() {
+				if c.stack[len(c.stack)-2] == ':' {
+					c.buf.WriteByte('\n')
+					c.writeIndent()
 				}
-				continue
-			case '}', ']':
-				c.stack = c.stack[:len(c.stack)-1]
-				if len(c.stack) > 1 {
-					c.indent -= 2
+				if c.stack[len(c.stack)-1] == '[' {
+					c.buf.WriteString("- ")
 				}
-			}
-		} else {
-			switch c.stack[len(c.stack)-1] {
-			case '{':
-				if err := c.writeValue(token); err != nil {
-					return err
+			} else {
+				if c.stack[len(c.stack)-2] == ':' {
+					c.buf.WriteByte(' ')
 				}
-				c.buf.WriteByte(':')
-				c.stack[len(c.stack)-1] = ':'
-				continue
-			case ':':
-				c.buf.WriteByte(' ')
-				fallthrough
-			default:
-				if err := c.writeValue(token); err != nil {
-					return err
+				if c.stack[len(c.stack)-1] == '{' {
+					c.buf.WriteString("{}\n")
+				} else {
+					c.buf.WriteString("[]\n")
 				}
-				c.buf.WriteByte('\n')
+			}
+			return nil
+		case '}', ']':
+			c.stack = c.stack[:len(c.stack)-1]
+			if len(c.stack) > 1 {
+				c.indent -= 2
 			}
 		}
-		if dec.More() {
-			c.writeIndent()
-			switch c.stack[len(c.stack)-1] {
-			case ':':
-				c.stack[len(c.stack)-1] = '{'
-			case '[':
-				c.buf.WriteString("- ")
-			case '.':
-				c.buf.WriteString("---\n")
+	} else {
+		switch c.stack[len(c.stack)-1] {
+		case '{':
+			if key, ok := token.(string); ok {
+				c.key = key
+			}
+			if comment != "" {
+				c.writeComment(comment)
+			}
+			if err := c.writeValue(token); err != nil {
+				return err
+			}
+			c.buf.WriteByte(':')
+			c.stack[len(c.stack)-1] = ':'
+			return nil
+		case ':':
+			c.buf.WriteByte(' ')
+			fallthrough
+		default:
+			if err := c.writeValue(token); err != nil {
+				return err
+			}
+			c.buf.WriteByte('\n')
+		}
+	}
+	if len(c.stack) == 1 {
+		c.writeDocumentEnd()
+		if c.verifier != nil {
+			if err := c.verifyDocument(); err != nil {
+				c.buf.Reset()
+				return err
+			}
+		}
+		if c.verifier != nil || c.flushPolicy == FlushByDocument {
+			if err := c.flush(); err != nil {
+				return err
+			}
+		}
+	} else if dec.More() {
+		c.writeIndent()
+		switch c.stack[len(c.stack)-1] {
+		case ':':
+			c.stack[len(c.stack)-1] = '{'
+		case '[':
+			c.buf.WriteString("- ")
+		}
+	}
+	return nil
+}
+
+func (c *converter) writeDocumentStart() {
+	if c.documents == 0 {
+		for _, directive := range c.directives {
+			c.buf.WriteString(directive)
+			c.buf.WriteByte('\n')
+		}
+	}
+	if c.documents > 0 || c.docStart || len(c.directives) > 0 {
+		c.buf.WriteString("---\n")
+	}
+	if c.source != "" {
+		c.writeComment("Source: " + c.source)
+	}
+	c.documents++
+}
+
+func (c *converter) writeDocumentEnd() {
+	if c.docEnd {
+		c.buf.WriteString("...\n")
+	}
+}
+
+// writeComment writes the comment lines, assuming that the indentation of the
+// first line is already written, and writes the indentation for the next line.
+// The text is split by the line breaks of YAML 1.1 and 1.2, and non-printable
+// characters are escaped as in double-quoted strings.
+func (c *converter) writeComment(text string) {
+	for text != "" {
+		s := text
+		if i := strings.IndexAny(text, "\r\n\u0085\u2028\u2029"); i >= 0 {
+			_, size := utf8.DecodeRuneInString(text[i:])
+			if strings.HasPrefix(text[i:], "\r\n") {
+				size = 2
 			}
+			s, text = text[:i], text[i+size:]
+		} else {
+			text = ""
 		}
+		if s = strings.TrimRight(s, " \t"); s == "" {
+			c.buf.WriteString("#\n")
+		} else {
+			c.buf.WriteString("# ")
+			c.writeCommentLine(s)
+			c.buf.WriteByte('\n')
+		}
+		c.writeIndent()
+	}
+}
+
+func (c *converter) writeCommentLine(s string) {
+	start := 0
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == '\t' || ' ' <= r && r <= '~' ||
+			!c.ascii && !isNonPrintable(r) && (r != utf8.RuneError || size > 1) {
+			i += size
+			continue
+		}
+		c.buf.WriteString(s[start:i])
+		if r == utf8.RuneError && size == 1 {
+			c.writeEscapedByte(s[i])
+		} else {
+			c.writeEscapedRune(r)
+		}
+		i += size
+		start = i
+	}
+	c.buf.WriteString(s[start:])
+}
+
+// isNonPrintable reports whether the rune is escaped in double-quoted strings
+// even if the output is not restricted to ASCII.
+func isNonPrintable(r rune) bool {
+	return r <= '\u009F' || r == '\u2028' || r == '\u2029' ||
+		'\uFDD0' <= r && (r == '\uFEFF' || r <= '\uFDEF' ||
+			r == '\uFFFE' || r == '\uFFFF')
+}
+
+const hexDigits = "0123456789ABCDEF"
+
+func (c *converter) writeEscapedByte(b byte) {
+	c.buf.Write([]byte{'\\', 'x', hexDigits[b>>4], hexDigits[b&0xF]})
+}
+
+func (c *converter) writeEscapedRune(r rune) {
+	if r <= '\u009F' {
+		c.writeEscapedByte(byte(r))
+	} else if r <= '\uFFFF' {
+		c.buf.Write([]byte{
+			'\\', 'u', hexDigits[r>>12], hexDigits[r>>8&0xF], hexDigits[r>>4&0xF], hexDigits[r&0xF],
+		})
+	} else {
+		c.buf.Write([]byte{
+			'\\', 'U', '0', '0', hexDigits[r>>20], hexDigits[r>>16&0xF],
+			hexDigits[r>>12&0xF], hexDigits[r>>8&0xF], hexDigits[r>>4&0xF], hexDigits[r&0xF],
+		})
 	}
 }
 
@@ -149,51 +484,139 @@ func (c *converter) writeValue(v any) error {
 			c.buf.WriteString("false")
 		}
 	case json.Number:
-		c.buf.WriteString(string(v))
+		c.writeNumber(string(v))
 	case string:
-		c.writeString(v)
+		switch {
+		case c.nextStyle == DoubleQuotedStyle:
+			c.writeDoubleQuotedString(v)
+		case c.nextStyle == LiteralStyle && (!c.ascii || isASCII(v)) &&
+			utf8.ValidString(v) && !quoteMultiLineStringPattern.MatchString(v):
+			c.writeBlockStyleString(v)
+		case c.isBinaryString(v):
+			c.writeBinaryString(v)
+		default:
+			c.writeString(v)
+		}
 	}
-	if c.buf.Len() > 4*1024 {
+	if c.buf.Len() > c.bufferSize && c.verifier == nil &&
+		(c.flushPolicy == FlushBySize || c.flushPolicy == FlushByDocument) {
 		return c.flush()
 	}
 	return nil
 }
 
+func (c *converter) writeNumber(v string) {
+	switch c.numberFormat {
+	case CanonicalNumbers:
+		v = canonicalNumber(v)
+	case SafeNumbers:
+		if !isSafeInteger(v) {
+			c.buf.WriteByte('"')
+			c.buf.WriteString(v)
+			c.buf.WriteByte('"')
+			return
+		}
+	}
+	if c.numberTags {
+		if strings.ContainsAny(v, ".eE") {
+			if f, _ := strconv.ParseFloat(v, 64); math.IsInf(f, 0) {
+				c.buf.WriteString("!!float ")
+			}
+		} else if _, err := strconv.ParseInt(v, 10, 64); err != nil {
+			c.buf.WriteString("!!int ")
+		}
+	}
+	c.buf.WriteString(v)
+}
+
+func canonicalNumber(v string) string {
+	if !strings.ContainsAny(v, ".eE") {
+		if v == "-0" {
+			return "0"
+		}
+		return v
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return v
+	}
+	if f == 0 {
+		return "0"
+	}
+	return formatFloat(f, 64)
+}
+
+// ref: floatEncoder#encode in encoding/json
+func formatFloat(f float64, bits int) string {
+	format := byte('f')
+	if abs := math.Abs(f); abs != 0 && (bits == 64 && (abs < 1e-6 || abs >= 1e21) ||
+		bits == 32 && (float32(abs) < 1e-6 || float32(abs) >= 1e21)) {
+		format = 'e'
+	}
+	bs := strconv.AppendFloat(nil, f, format, -1, bits)
+	if format == 'e' {
+		// clean up e-09 to e-9
+		if n := len(bs); n >= 4 && bs[n-4] == 'e' && bs[n-3] == '-' && bs[n-2] == '0' {
+			bs[n-2] = bs[n-1]
+			bs = bs[:n-1]
+		}
+	}
+	return string(bs)
+}
+
+func isSafeInteger(v string) bool {
+	if strings.ContainsAny(v, ".eE") {
+		return true
+	}
+	v = strings.TrimPrefix(v, "-")
+	const maxSafeInteger = "9007199254740991"
+	return len(v) < len(maxSafeInteger) ||
+		len(v) == len(maxSafeInteger) && v <= maxSafeInteger
+}
+
 // These patterns match more than the specifications,
 // but it is okay to quote for parsers just in case.
+const (
+	// implicit types of plain scalars, matched against the entire string
+	quoteImplicitTypes = `(?i:` +
+		// tag:yaml.org,2002:null
+		`|~|null` +
+		// tag:yaml.org,2002:bool
+		`|true|false|y(?:es)?|no?|o(?:n|ff)` +
+		// tag:yaml.org,2002:int, tag:yaml.org,2002:float
+		`|[-+]?(?:0(?:b[01_]+|o[0-7_]+|x[0-9a-f_]+)` + // base 2, 8, 16
+		`|(?:[0-9][0-9_]*(?::[0-5]?[0-9])*(?:\.[0-9_]*)?` +
+		`|\.[0-9_]+)(?:E[-+]?[0-9]+)?` + // base 10, 60
+		`|\.inf)|\.nan` + // infinities, not-a-number
+		// tag:yaml.org,2002:timestamp
+		`|\d\d\d\d-\d\d?-\d\d?` + // date
+		`(?:(?:T|\s+)\d\d?:\d\d?:\d\d?(?:\.\d*)?` + // time
+		`(?:\s*(?:Z|[-+]\d\d?(?::\d\d?)?))?)?` + // time zone
+		// tag:yaml.org,2002:merge, tag:yaml.org,2002:value
+		`|<<|=` +
+		`)`
+	// indicators at the beginning of the string
+	quoteLeadingIndicators = `` +
+		// c-indicator - '-' - '?' - ':', leading white space
+		"[,\\[\\]{}#&*!|>'\"%@` \\t]" +
+		// sequence entry, document markers, mapping key
+		`|(?:-(?:--)?|\.\.\.|\?)(?:[ \t]|$)`
+	// indicators and special characters anywhere in the string
+	quoteIndicators = `` +
+		// mapping value
+		`:(?:[ \t]|$)` +
+		// trailing white space, comment
+		`|[ \t](?:#|$)` +
+		// C0 control codes - '\n', DEL
+		"|[\u0000-\u0009\u000B-\u001F\u007F" +
+		// C1 control codes, line/paragraph separator, BOM, noncharacters
+		"\u0080-\u009F\u2028\u2029\uFEFF\uFDD0-\uFDEF\uFFFE\uFFFF]"
+)
+
 var (
-	quoteSingleLineStringPattern = regexp.MustCompile(
-		`^(?:` +
-			`(?i:` +
-			// tag:yaml.org,2002:null
-			`|~|null` +
-			// tag:yaml.org,2002:bool
-			`|true|false|y(?:es)?|no?|o(?:n|ff)` +
-			// tag:yaml.org,2002:int, tag:yaml.org,2002:float
-			`|[-+]?(?:0(?:b[01_]+|o[0-7_]+|x[0-9a-f_]+)` + // base 2, 8, 16
-			`|(?:[0-9][0-9_]*(?::[0-5]?[0-9])*(?:\.[0-9_]*)?` +
-			`|\.[0-9_]+)(?:E[-+]?[0-9]+)?` + // base 10, 60
-			`|\.inf)|\.nan` + // infinities, not-a-number
-			// tag:yaml.org,2002:timestamp
-			`|\d\d\d\d-\d\d?-\d\d?` + // date
-			`(?:(?:T|\s+)\d\d?:\d\d?:\d\d?(?:\.\d*)?` + // time
-			`(?:\s*(?:Z|[-+]\d\d?(?::\d\d?)?))?)?` + // time zone
-			// tag:yaml.org,2002:merge, tag:yaml.org,2002:value
-			`|<<|=` +
-			`)$` +
-			// c-indicator - '-' - '?' - ':', leading white space
-			"|[,\\[\\]{}#&*!|>'\"%@` \\t]" +
-			// sequence entry, document markers, mapping key
-			`|(?:-(?:--)?|\.\.\.|\?)(?:[ \t]|$)` +
-			`)` +
-			// mapping value
-			`|:(?:[ \t]|$)` +
-			// trailing white space, comment
-			`|[ \t](?:#|$)` +
-			// C0 control codes - '\n', DEL
-			"|[\u0000-\u0009\u000B-\u001F\u007F" +
-			// C1 control codes, line/paragraph separator, BOM, noncharacters
-			"\u0080-\u009F\u2028\u2029\uFEFF\uFDD0-\uFDEF\uFFFE\uFFFF]",
+	quoteSingleLineStringPattern = newQuoteSingleLineStringPattern(quoteImplicitTypes)
+	quoteIndicatorPattern        = regexp.MustCompile(
+		`^(?:` + quoteLeadingIndicators + `)|` + quoteIndicators,
 	)
 	quoteMultiLineStringPattern = regexp.MustCompile(
 		`` +
@@ -210,13 +633,23 @@ func (c *converter) writeString(v string) {
 	switch {
 	default:
 		c.buf.WriteString(v)
+	case c.invalidUTF8 == PreserveInvalidUTF8 && !utf8.ValidString(v),
+		c.ascii && !isASCII(v):
+		c.writeDoubleQuotedString(v)
 	case strings.ContainsRune(v, '\n'):
 		if !quoteMultiLineStringPattern.MatchString(v) {
 			c.writeBlockStyleString(v)
 			break
 		}
 		fallthrough
-	case quoteSingleLineStringPattern.MatchString(v):
+	case c.profile.quotePattern().MatchString(v):
+		// the multi-line strings falling through here cannot be plain scalars
+		if c.stringTags && v != "" && !strings.ContainsRune(v, '\n') &&
+			!quoteIndicatorPattern.MatchString(v) {
+			c.buf.WriteString("!!str ")
+			c.buf.WriteString(v)
+			break
+		}
 		c.writeDoubleQuotedString(v)
 	}
 }
@@ -247,9 +680,57 @@ func (c *converter) writeBlockStyleString(v string) {
 	}
 }
 
+func isASCII(s string) bool {
+	for i := range len(s) {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *converter) isBinaryString(v string) bool {
+	if c.binaryKeys == nil && !c.binaryDetect && c.binarySchema == nil ||
+		len(v)%4 != 0 || v == "" {
+		return false
+	}
+	var keyMatch bool
+	switch c.stack[len(c.stack)-1] {
+	case '{':
+		return false
+	case ':':
+		keyMatch = c.binaryKeys != nil && c.binaryKeys.MatchString(c.key)
+	}
+	if !keyMatch && c.binarySchema != nil {
+		keyMatch = c.binarySchema.next.lookup(func(s *schema) string {
+			return s.contentEncoding
+		}) == "base64"
+	}
+	if !keyMatch && (!c.binaryDetect || len(v) < 64) {
+		return false
+	}
+	bs, err := base64.StdEncoding.DecodeString(v)
+	return err == nil && (keyMatch || !utf8.Valid(bs) ||
+		bytes.ContainsFunc(bs, func(r rune) bool {
+			return r < ' ' && r != '\t' && r != '\n' && r != '\r' || r == '\x7F'
+		}))
+}
+
+func (c *converter) writeBinaryString(v string) {
+	var sb strings.Builder
+	sb.Grow(len(v) + len(v)/76 + 1)
+	for v != "" {
+		n := min(len(v), 76)
+		sb.WriteString(v[:n])
+		sb.WriteByte('\n')
+		v = v[n:]
+	}
+	c.buf.WriteString("!!binary ")
+	c.writeBlockStyleString(sb.String())
+}
+
 // ref: encodeState#string in encoding/json
 func (c *converter) writeDoubleQuotedString(s string) {
-	const hex = "0123456789ABCDEF"
 	c.buf.WriteByte('"')
 	start := 0
 	for i := 0; i < len(s); {
@@ -277,26 +758,27 @@ func (c *converter) writeDoubleQuotedString(s string) {
 			case '\t':
 				c.buf.WriteString(`\t`)
 			default:
-				c.buf.Write([]byte{'\\', 'x', hex[b>>4], hex[b&0xF]})
+				c.writeEscapedByte(b)
 			}
 			i++
 			start = i
 			continue
 		}
 		r, size := utf8.DecodeRuneInString(s[i:])
-		if r <= '\u009F' || r == '\u2028' || r == '\u2029' ||
-			'\uFDD0' <= r && (r == '\uFEFF' || r <= '\uFDEF' ||
-				r == '\uFFFE' || r == '\uFFFF') {
+		if r == utf8.RuneError && size == 1 {
 			if start < i {
 				c.buf.WriteString(s[start:i])
 			}
-			if r <= '\u009F' {
-				c.buf.Write([]byte{'\\', 'x', hex[r>>4], hex[r&0xF]})
-			} else {
-				c.buf.Write([]byte{
-					'\\', 'u', hex[r>>12], hex[r>>8&0xF], hex[r>>4&0xF], hex[r&0xF],
-				})
+			c.writeEscapedByte(s[i])
+			i++
+			start = i
+			continue
+		}
+		if c.ascii || isNonPrintable(r) {
+			if start < i {
+				c.buf.WriteString(s[start:i])
 			}
+			c.writeEscapedRune(r)
 			i += size
 			start = i
 			continue
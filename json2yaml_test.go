@@ -4,19 +4,24 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"regexp"
 	"strings"
 	"testing"
 
 	"github.com/itchyny/json2yaml"
 )
 
-func TestConvert(t *testing.T) {
-	testCases := []struct {
-		name string
-		src  string
-		want string
-		err  string
-	}{
+type convertTestCase struct {
+	name string
+	src  string
+	want string
+	err  string
+}
+
+// convertTestCases returns the test cases of Convert, which are also used by
+// the tests of the other APIs and the seeds of the fuzz test.
+func convertTestCases() []convertTestCase {
+	return []convertTestCase{
 		{
 			name: "null",
 			src:  "null",
@@ -317,7 +322,10 @@ w: |-
 			want: strings.Repeat("- test\n", 1000),
 		},
 	}
-	for _, tc := range testCases {
+}
+
+func TestConvert(t *testing.T) {
+	for _, tc := range convertTestCases() {
 		t.Run(tc.name, func(t *testing.T) {
 			var sb strings.Builder
 			err := json2yaml.Convert(&sb, strings.NewReader(tc.src))
@@ -340,6 +348,256 @@ w: |-
 	}
 }
 
+func TestConvertOptions(t *testing.T) {
+	const (
+		binary = "AAECAwQFBgcICQoLDA0ODxAREhMUFRYXGBkaGxwdHh8gISIjJCUmJygpKissLS4vMDEyMzQ1Njc4" +
+			"OTo7PD0+P0BBQkNERUZHSElKS0xNTk9QUVJTVFVWV1hZ"
+		text = "aGVsbG8sIHdvcmxkISB0aGlzIGlzIGEgcGxhaW4gdGV4dCBtZXNzYWdlIHdoaWNoIGlzIGxvbmcg" +
+			"ZW5vdWdoLg=="
+	)
+	testCases := []struct {
+		name string
+		src  string
+		opts []json2yaml.Option
+		want string
+		err  string
+	}{
+		{
+			name: "ascii",
+			src: `{"foo": ["bar", "１２３４５", "\u00e9\n", "\u0080\u00a0", "\ud83d\ude00", "\u2028"],
+				"é": {"あ": "\t"}, "\ud83d\ude00\n": null}`,
+			opts: []json2yaml.Option{json2yaml.WithASCII()},
+			want: `foo:
+  - bar
+  - "\uFF11\uFF12\uFF13\uFF14\uFF15"
+  - "\u00E9\n"
+  - "\x80\u00A0"
+  - "\U0001F600"
+  - "\u2028"
+"\u00E9":
+  "\u3042": "\t"
+"\U0001F600\n": null
+`,
+		},
+		{
+			name: "verbatim numbers",
+			src:  `[0, -0, 1E+9, 1.0e00, 0.10, 12345678901234567890]`,
+			opts: []json2yaml.Option{json2yaml.WithNumberFormat(json2yaml.VerbatimNumbers)},
+			want: "- 0\n- -0\n- 1E+9\n- 1.0e00\n- 0.10\n- 12345678901234567890\n",
+		},
+		{
+			name: "canonical numbers",
+			src: `[0, -0, 1E+9, 1.0e00, 0.10, -0.0, 1e-7, 1.5E-10, 1e21, 123456789012345678901e-1,
+				3.14159265358979323846, 1e400, 12345678901234567890]`,
+			opts: []json2yaml.Option{json2yaml.WithNumberFormat(json2yaml.CanonicalNumbers)},
+			want: `- 0
+- 0
+- 1000000000
+- 1
+- 0.1
+- 0
+- 1e-7
+- 1.5e-10
+- 1e+21
+- 12345678901234567000
+- 3.141592653589793
+- 1e400
+- 12345678901234567890
+`,
+		},
+		{
+			name: "safe numbers",
+			src: `{"x": [0, -9007199254740991, 9007199254740991, 9007199254740992, -9007199254740992,
+				12345678901234567890, 1.2345678901234567890, 1e400]}`,
+			opts: []json2yaml.Option{json2yaml.WithNumberFormat(json2yaml.SafeNumbers)},
+			want: `x:
+  - 0
+  - -9007199254740991
+  - 9007199254740991
+  - "9007199254740992"
+  - "-9007199254740992"
+  - "12345678901234567890"
+  - 1.2345678901234567890
+  - 1e400
+`,
+		},
+		{
+			name: "string tags",
+			src: `{"0664": ["0664", "yes", "~", "", "<<", "1e2", "2022-08-04", "2022-01-01 12:13:14",
+				"2022-01-01\r12:13:14", "foo", "- 1", "#1", "0664\n", "\n", "\n\n a", "ⅰ"], "=": "1:2"}`,
+			opts: []json2yaml.Option{json2yaml.WithStringTags(), json2yaml.WithASCII()},
+			want: `!!str 0664:
+  - !!str 0664
+  - !!str yes
+  - !!str ~
+  - ""
+  - !!str <<
+  - !!str 1e2
+  - !!str 2022-08-04
+  - !!str 2022-01-01 12:13:14
+  - "2022-01-01\r12:13:14"
+  - foo
+  - "- 1"
+  - "#1"
+  - |
+    0664
+  - "\n"
+  - "\n\n a"
+  - "\u2170"
+!!str =: !!str 1:2
+`,
+		},
+		{
+			name: "number tags",
+			src: `[0, -9223372036854775808, 9223372036854775807, 9223372036854775808, -9223372036854775809,
+				1.5, 1e308, 1e309, -1e400, 1e-400]`,
+			opts: []json2yaml.Option{json2yaml.WithNumberTags()},
+			want: `- 0
+- -9223372036854775808
+- 9223372036854775807
+- !!int 9223372036854775808
+- !!int -9223372036854775809
+- 1.5
+- 1e308
+- !!float 1e309
+- !!float -1e400
+- 1e-400
+`,
+		},
+		{
+			name: "number tags with safe numbers",
+			src:  `[9223372036854775808, 1e400]`,
+			opts: []json2yaml.Option{json2yaml.WithNumberTags(), json2yaml.WithNumberFormat(json2yaml.SafeNumbers)},
+			want: "- \"9223372036854775808\"\n- !!float 1e400\n",
+		},
+		{
+			name: "binary keys",
+			src: `{"cert": "` + binary + `", "data": "aGVsbG8=", "image": "not base64", "name": "aGVsbG8=",
+				"` + binary + `": {"image": ["aGVsbG8="], "data": "` + text + `"}}`,
+			opts: []json2yaml.Option{json2yaml.WithBinaryKeys(regexp.MustCompile(`^(?:cert|data|image)$`))},
+			want: `cert: !!binary |
+  ` + binary[:76] + `
+  ` + binary[76:] + `
+data: !!binary |
+  aGVsbG8=
+image: not base64
+name: aGVsbG8=
+` + binary + `:
+  image:
+    - aGVsbG8=
+  data: !!binary |
+    ` + text[:76] + `
+    ` + text[76:] + `
+`,
+		},
+		{
+			name: "binary detection",
+			src:  `["` + binary + `", "` + text + `", "aGVsbG8=", {"x": "` + binary + `"}, "` + binary + `="]`,
+			opts: []json2yaml.Option{json2yaml.WithBinaryDetection()},
+			want: `- !!binary |
+  ` + binary[:76] + `
+  ` + binary[76:] + `
+- ` + text + `
+- aGVsbG8=
+- x: !!binary |
+    ` + binary[:76] + `
+    ` + binary[76:] + `
+- ` + binary + `=
+`,
+		},
+		{
+			name: "document start",
+			src:  `{"x": 1} [] "foo"`,
+			opts: []json2yaml.Option{json2yaml.WithDocumentStart()},
+			want: "---\nx: 1\n---\n[]\n---\nfoo\n",
+		},
+		{
+			name: "document end",
+			src:  `{"x": 1} [] "foo"`,
+			opts: []json2yaml.Option{json2yaml.WithDocumentEnd()},
+			want: "x: 1\n...\n---\n[]\n...\n---\nfoo\n...\n",
+		},
+		{
+			name: "document start and end with directives",
+			src:  `{"x": [1]} {"z": {}}`,
+			opts: []json2yaml.Option{
+				json2yaml.WithDocumentStart(), json2yaml.WithDocumentEnd(),
+				json2yaml.WithYAMLDirective("1.2"), json2yaml.WithTagDirective("!e!", "tag:example.com,2000:"),
+			},
+			want: "%YAML 1.2\n%TAG !e! tag:example.com,2000:\n---\nx:\n  - 1\n...\n---\nz: {}\n...\n",
+		},
+		{
+			name: "directives without documents",
+			src:  ` `,
+			opts: []json2yaml.Option{json2yaml.WithYAMLDirective("1.2"), json2yaml.WithDocumentEnd()},
+			want: "",
+		},
+		{
+			name: "document end after error",
+			src:  `{"x": 1} {"z": ]`,
+			opts: []json2yaml.Option{json2yaml.WithYAMLDirective("1.2"), json2yaml.WithDocumentEnd()},
+			want: "%YAML 1.2\n---\nx: 1\n...\n---\nz:\n",
+			err:  "invalid character ']'",
+		},
+		{
+			name: "header comment",
+			src:  `{"x": 1} {"y": 2}`,
+			opts: []json2yaml.Option{json2yaml.WithHeaderComment("Code generated by json2yaml. DO NOT EDIT.\n\nfoo\n")},
+			want: "# Code generated by json2yaml. DO NOT EDIT.\n#\n# foo\nx: 1\n---\n\"y\": 2\n",
+		},
+		{
+			name: "header comment with line breaks and non-printable characters",
+			src:  `{"a": 1}`,
+			opts: []json2yaml.Option{json2yaml.WithHeaderComment(
+				"first\rinjected: 2\r\nthird \u0085fourth\u2028fifth\u2029\x00\x7F\u00A0\ufeff\xff\U0001F600é\t\n")},
+			want: "# first\n# injected: 2\n# third\n# fourth\n# fifth\n# \\x00\\x7F\u00A0\\uFEFF\\xFF\U0001F600é\na: 1\n",
+		},
+		{
+			name: "header comment in ascii",
+			src:  `{"a": 1}`,
+			opts: []json2yaml.Option{json2yaml.WithHeaderComment("é\U0001F600"), json2yaml.WithASCII()},
+			want: "# \\u00E9\\U0001F600\na: 1\n",
+		},
+		{
+			name: "header comment without documents",
+			src:  ``,
+			opts: []json2yaml.Option{json2yaml.WithHeaderComment("header")},
+			want: "# header\n",
+		},
+		{
+			name: "source comment",
+			src:  `{"x": 1} {"y": 2}`,
+			opts: []json2yaml.Option{
+				json2yaml.WithHeaderComment("header"), json2yaml.WithSourceComment("path/to/foo.json"),
+				json2yaml.WithYAMLDirective("1.2"),
+			},
+			want: "# header\n%YAML 1.2\n---\n# Source: path/to/foo.json\nx: 1\n" +
+				"---\n# Source: path/to/foo.json\n\"y\": 2\n",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var sb strings.Builder
+			err := json2yaml.Convert(&sb, strings.NewReader(tc.src), tc.opts...)
+			if got, want := diff(sb.String(), tc.want); got != want {
+				t.Fatalf("should write\n  %q\nbut got\n  %q", want, got)
+			}
+			if tc.err == "" {
+				if err != nil {
+					t.Fatalf("should not raise an error but got: %s", err)
+				}
+			} else {
+				if err == nil {
+					t.Fatalf("should raise an error %q but got no error", tc.err)
+				}
+				if !strings.Contains(err.Error(), tc.err) {
+					t.Fatalf("should raise an error %q but got error %q", tc.err, err)
+				}
+			}
+		})
+	}
+}
+
 type errWriter struct{}
 
 func (errWriter) Write(bs []byte) (int, error) {
@@ -350,6 +608,7 @@ func TestConvertError(t *testing.T) {
 	testCases := []struct {
 		name string
 		src  string
+		opts []json2yaml.Option
 		err  string
 	}{
 		{
@@ -372,10 +631,22 @@ func TestConvertError(t *testing.T) {
 			src:  "[" + strings.Repeat(`"test",`, 1000) + `"test"]`,
 			err:  fmt.Sprint(len("- test\n")*(4*1024/len("- test\n")+1) - 1),
 		},
+		{
+			name: "flush by document",
+			src:  `[1,2] 3`,
+			opts: []json2yaml.Option{json2yaml.WithFlushPolicy(json2yaml.FlushByDocument)},
+			err:  fmt.Sprint(len("- 1\n- 2\n")),
+		},
+		{
+			name: "flush by line",
+			src:  `{"a":[1,2]}`,
+			opts: []json2yaml.Option{json2yaml.WithFlushPolicy(json2yaml.FlushByLine)},
+			err:  fmt.Sprint(len("a:\n")),
+		},
 	}
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			err := json2yaml.Convert(errWriter{}, strings.NewReader(tc.src))
+			err := json2yaml.Convert(errWriter{}, strings.NewReader(tc.src), tc.opts...)
 			if err == nil {
 				t.Fatalf("should raise an error %q but got no error", tc.err)
 			}
@@ -386,6 +657,69 @@ func TestConvertError(t *testing.T) {
 	}
 }
 
+type writesRecorder struct {
+	writes []string
+}
+
+func (w *writesRecorder) Write(bs []byte) (int, error) {
+	if len(bs) > 0 {
+		w.writes = append(w.writes, string(bs))
+	}
+	return len(bs), nil
+}
+
+func TestConvertFlushPolicy(t *testing.T) {
+	testCases := []struct {
+		name string
+		src  string
+		opts []json2yaml.Option
+		want []string
+	}{
+		{
+			name: "flush by size",
+			src:  `{"a":[1,2]} {"b":3}`,
+			want: []string{"a:\n  - 1\n  - 2\n---\nb: 3\n"},
+		},
+		{
+			name: "flush by buffer size",
+			src:  `{"a":[1,2]} {"b":3}`,
+			opts: []json2yaml.Option{json2yaml.WithBufferSize(8)},
+			want: []string{"a:\n  - 1\n  - 2", "\n---\nb: 3", "\n"},
+		},
+		{
+			name: "flush by document",
+			src:  `{"a":[1,2]} {"b":3}`,
+			opts: []json2yaml.Option{json2yaml.WithFlushPolicy(json2yaml.FlushByDocument)},
+			want: []string{"a:\n  - 1\n  - 2\n", "---\nb: 3\n"},
+		},
+		{
+			name: "flush by line",
+			src:  `{"a":[1,2],"b":"c\nd\n"} {"e":3}`,
+			opts: []json2yaml.Option{json2yaml.WithFlushPolicy(json2yaml.FlushByLine)},
+			want: []string{"a:\n", "  - 1\n", "  - 2\n", "b: |\n  c\n  d\n", "---\n", "e: 3\n"},
+		},
+		{
+			name: "flush at end",
+			src:  `{"a":[1,2]} {"b":3}`,
+			opts: []json2yaml.Option{
+				json2yaml.WithFlushPolicy(json2yaml.FlushAtEnd), json2yaml.WithBufferSize(8),
+			},
+			want: []string{"a:\n  - 1\n  - 2\n---\nb: 3\n"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var w writesRecorder
+			if err := json2yaml.Convert(&w, strings.NewReader(tc.src), tc.opts...); err != nil {
+				t.Fatalf("should not raise an error but got: %s", err)
+			}
+			if got, want := fmt.Sprintf("%q", w.writes), fmt.Sprintf("%q", tc.want); got != want {
+				t.Fatalf("should write\n  %s\nbut got\n  %s", want, got)
+			}
+		})
+	}
+}
+
 func join(xs []string) string {
 	var sb strings.Builder
 	n := 5*(len(xs)-1) + 1
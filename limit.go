@@ -0,0 +1,132 @@
+package json2yaml
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Errors returned on exceeding the limits of the conversion.
+var (
+	ErrDepthLimit    = errors.New("depth limit exceeded")
+	ErrDocumentLimit = errors.New("document limit exceeded")
+	ErrStringLimit   = errors.New("string length limit exceeded")
+	ErrSizeLimit     = errors.New("output size limit exceeded")
+)
+
+// LimitError is returned on exceeding a limit of the conversion, and wraps
+// one of the errors above. Offset is the byte offset in the input, or in the
+// output for [ErrSizeLimit], and is -1 when the tokens are not read from JSON.
+type LimitError struct {
+	Err    error
+	Limit  int64
+	Offset int64
+}
+
+func (err *LimitError) Error() string {
+	if err.Offset < 0 {
+		return fmt.Sprintf("%s (limit %d)", err.Err, err.Limit)
+	}
+	return fmt.Sprintf("%s (limit %d) at byte offset %d", err.Err, err.Limit, err.Offset)
+}
+
+func (err *LimitError) Unwrap() error {
+	return err.Err
+}
+
+// limits of the conversion, where zero means no limit
+type limits struct {
+	depth        int
+	documents    int
+	stringLength int
+	outputSize   int64
+	written      int64
+}
+
+// checkLimits checks the token against the limits, before the conversion.
+func (c *converter) checkLimits(token json.Token) error {
+	switch v := token.(type) {
+	case json.Delim:
+		if c.limits.depth > 0 && (v == '{' || v == '[') &&
+			len(c.stack) > c.limits.depth {
+			return &LimitError{ErrDepthLimit, int64(c.limits.depth), c.offset}
+		}
+	case string:
+		if c.limits.stringLength > 0 && len(v) > c.limits.stringLength {
+			return &LimitError{ErrStringLimit, int64(c.limits.stringLength), c.offset}
+		}
+	}
+	if c.limits.documents > 0 && len(c.stack) == 1 &&
+		c.documents >= c.limits.documents {
+		return &LimitError{ErrDocumentLimit, int64(c.limits.documents), c.offset}
+	}
+	return nil
+}
+
+// checkOutputSize flushes the buffer on exceeding the output size limit.
+func (c *converter) checkOutputSize() error {
+	if c.limits.outputSize > 0 &&
+		c.limits.written+int64(c.buf.Len()) > c.limits.outputSize {
+		return c.flush()
+	}
+	return nil
+}
+
+// truncateOutput truncates the output at the last line within the output
+// size limit, and reports whether the output is truncated.
+func (c *converter) truncateOutput(bs []byte) ([]byte, bool) {
+	if c.limits.outputSize <= 0 {
+		return bs, false
+	}
+	n := c.limits.outputSize - c.limits.written
+	if int64(len(bs)) <= n {
+		c.limits.written += int64(len(bs))
+		return bs, false
+	}
+	bs = bs[:bytes.LastIndexByte(bs[:n], '\n')+1]
+	c.limits.written += int64(len(bs))
+	return bs, true
+}
+
+// stringLimitReader rejects the strings exceeding the limit in the raw input,
+// before the decoder allocates them. Since an escape sequence like \u0041 is
+// six bytes for one byte, the raw length is limited to six times the length.
+type stringLimitReader struct {
+	r        io.Reader
+	limit    int
+	length   int
+	inString bool
+	escaped  bool
+	offset   int64
+	err      error
+}
+
+func (r *stringLimitReader) Read(p []byte) (int, error) {
+	if r.err != nil {
+		return 0, r.err
+	}
+	n, err := r.r.Read(p)
+	for i, b := range p[:n] {
+		if !r.inString {
+			r.inString, r.length = b == '"', 0
+			continue
+		}
+		if r.escaped {
+			r.escaped = false
+		} else if b == '\\' {
+			r.escaped = true
+		} else if b == '"' {
+			r.inString = false
+			continue
+		}
+		if r.length++; r.length > 6*r.limit {
+			r.err = &LimitError{ErrStringLimit, int64(r.limit), r.offset + int64(i)}
+			r.offset += int64(i)
+			return i, nil
+		}
+	}
+	r.offset += int64(n)
+	return n, err
+}
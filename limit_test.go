@@ -0,0 +1,229 @@
+package json2yaml_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/itchyny/json2yaml"
+)
+
+func TestConvertLimits(t *testing.T) {
+	testCases := []struct {
+		name string
+		src  string
+		opts []json2yaml.Option
+		want string
+		err  error
+	}{
+		{
+			name: "depth limit",
+			src:  `{"a":[{"b":[]}],"c":{}}`,
+			opts: []json2yaml.Option{json2yaml.WithMaxDepth(4)},
+			want: "a:\n  - b: []\nc: {}\n",
+		},
+		{
+			name: "depth limit exceeded",
+			src:  `{"a":[{"b":[]}],"c":{}}`,
+			opts: []json2yaml.Option{json2yaml.WithMaxDepth(3)},
+			want: "a:\n  - b:\n",
+			err:  json2yaml.ErrDepthLimit,
+		},
+		{
+			name: "negative depth limit",
+			src:  `1 []`,
+			opts: []json2yaml.Option{json2yaml.WithMaxDepth(-1)},
+			want: "1\n---\n[]\n",
+		},
+		{
+			name: "document limit",
+			src:  `1 [2] {"a":3}`,
+			opts: []json2yaml.Option{json2yaml.WithMaxDocuments(3)},
+			want: "1\n---\n- 2\n---\na: 3\n",
+		},
+		{
+			name: "document limit exceeded",
+			src:  `1 [2] {"a":3}`,
+			opts: []json2yaml.Option{json2yaml.WithMaxDocuments(2)},
+			want: "1\n---\n- 2\n",
+			err:  json2yaml.ErrDocumentLimit,
+		},
+		{
+			name: "string length limit",
+			src:  `{"abc":["def","αβ"]}`,
+			opts: []json2yaml.Option{json2yaml.WithMaxStringLength(4)},
+			want: "abc:\n  - def\n  - αβ\n",
+		},
+		{
+			name: "string length limit exceeded by key",
+			src:  `{"a":"b","cdefg":"h"}`,
+			opts: []json2yaml.Option{json2yaml.WithMaxStringLength(4)},
+			want: "a: b\n",
+			err:  json2yaml.ErrStringLimit,
+		},
+		{
+			name: "string length limit exceeded by value",
+			src:  `["a","αβγ"]`,
+			opts: []json2yaml.Option{json2yaml.WithMaxStringLength(4)},
+			want: "- a\n- \n",
+			err:  json2yaml.ErrStringLimit,
+		},
+		{
+			name: "string length limit with escape sequences",
+			src:  `["\u0041\u0042\u0043\u0044","\"\\\"\\"]`,
+			opts: []json2yaml.Option{json2yaml.WithMaxStringLength(4)},
+			want: "- ABCD\n- \"\\\"\\\\\\\"\\\\\"\n",
+		},
+		{
+			name: "string length limit exceeded by large string",
+			src:  `["a","` + strings.Repeat("b", 1000000) + `"]`,
+			opts: []json2yaml.Option{json2yaml.WithMaxStringLength(4)},
+			want: "- a\n- \n",
+			err:  json2yaml.ErrStringLimit,
+		},
+		{
+			name: "output size limit",
+			src:  `{"a":"b","c":["d","e"]}`,
+			opts: []json2yaml.Option{json2yaml.WithMaxOutputSize(20)},
+			want: "a: b\nc:\n  - d\n  - e\n",
+		},
+		{
+			name: "output size limit exceeded",
+			src:  `{"a":"b","c":["d","e"]}`,
+			opts: []json2yaml.Option{json2yaml.WithMaxOutputSize(19)},
+			want: "a: b\nc:\n  - d\n",
+			err:  json2yaml.ErrSizeLimit,
+		},
+		{
+			name: "output size limit exceeded by large document",
+			src:  `[` + strings.Repeat(`"abcdefg",`, 1000) + `0]`,
+			opts: []json2yaml.Option{json2yaml.WithMaxOutputSize(5000)},
+			want: strings.Repeat("- abcdefg\n", 500),
+			err:  json2yaml.ErrSizeLimit,
+		},
+		{
+			name: "output size limit exceeded by header",
+			src:  `0`,
+			opts: []json2yaml.Option{
+				json2yaml.WithHeaderComment("header"), json2yaml.WithMaxOutputSize(10),
+			},
+			want: "# header\n",
+			err:  json2yaml.ErrSizeLimit,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var sb strings.Builder
+			err := json2yaml.Convert(&sb, strings.NewReader(tc.src), tc.opts...)
+			if got, want := diff(sb.String(), tc.want); got != want {
+				t.Fatalf("should write\n  %q\nbut got\n  %q", want, got)
+			}
+			if tc.err == nil {
+				if err != nil {
+					t.Fatalf("should not raise an error but got: %s", err)
+				}
+			} else {
+				if err == nil {
+					t.Fatalf("should raise an error %q but got no error", tc.err)
+				}
+				if !errors.Is(err, tc.err) {
+					t.Fatalf("should raise an error %q but got error %q", tc.err, err)
+				}
+			}
+		})
+	}
+}
+
+func TestConvertLimitError(t *testing.T) {
+	testCases := []struct {
+		name string
+		src  string
+		opts []json2yaml.Option
+		err  string
+	}{
+		{
+			name: "depth limit",
+			src:  `{"a":[{"b":[]}]}`,
+			opts: []json2yaml.Option{json2yaml.WithMaxDepth(3)},
+			err:  "depth limit exceeded (limit 3) at byte offset 12",
+		},
+		{
+			name: "document limit",
+			src:  `1 [2] 3`,
+			opts: []json2yaml.Option{json2yaml.WithMaxDocuments(2)},
+			err:  "document limit exceeded (limit 2) at byte offset 7",
+		},
+		{
+			name: "string length limit",
+			src:  `["a","bcdef"]`,
+			opts: []json2yaml.Option{json2yaml.WithMaxStringLength(4)},
+			err:  "string length limit exceeded (limit 4) at byte offset 12",
+		},
+		{
+			name: "string length limit in raw input",
+			src:  `["a","` + strings.Repeat("b", 100) + `"]`,
+			opts: []json2yaml.Option{json2yaml.WithMaxStringLength(4)},
+			err:  "string length limit exceeded (limit 4) at byte offset 30",
+		},
+		{
+			name: "output size limit",
+			src:  `{"a":"b","c":["d","e"]}`,
+			opts: []json2yaml.Option{json2yaml.WithMaxOutputSize(19)},
+			err:  "output size limit exceeded (limit 19) at byte offset 14",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := json2yaml.Convert(io.Discard, strings.NewReader(tc.src), tc.opts...)
+			var lerr *json2yaml.LimitError
+			if !errors.As(err, &lerr) {
+				t.Fatalf("should raise a limit error but got error %v", err)
+			}
+			if got := err.Error(); got != tc.err {
+				t.Fatalf("should raise an error %q but got error %q", tc.err, got)
+			}
+		})
+	}
+
+	err := json2yaml.ConvertTokens(io.Discard, &tokenSliceReader{
+		tokens: []json.Token{json.Delim('['), json.Delim('[')}}, json2yaml.WithMaxDepth(1))
+	if want := "depth limit exceeded (limit 1)"; err == nil || err.Error() != want {
+		t.Fatalf("should raise an error %q but got error %v", want, err)
+	}
+}
+
+type cancelReader struct {
+	r      io.Reader
+	cancel context.CancelFunc
+}
+
+func (r *cancelReader) Read(p []byte) (int, error) {
+	r.cancel()
+	return r.r.Read(p[:1])
+}
+
+func TestConvertContext(t *testing.T) {
+	var sb strings.Builder
+	err := json2yaml.ConvertContext(context.Background(), &sb, strings.NewReader(`{"a":1}`))
+	if err != nil {
+		t.Fatalf("should not raise an error but got: %s", err)
+	}
+	if got, want := sb.String(), "a: 1\n"; got != want {
+		t.Fatalf("should write\n  %q\nbut got\n  %q", want, got)
+	}
+
+	sb.Reset()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	err = json2yaml.ConvertContext(ctx, &sb,
+		&cancelReader{strings.NewReader(`[1,2,3]`), cancel})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("should raise an error %q but got error %q", context.Canceled, err)
+	}
+	if got, want := sb.String(), "- \n"; got != want {
+		t.Fatalf("should write\n  %q\nbut got\n  %q", want, got)
+	}
+}
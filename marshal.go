@@ -0,0 +1,672 @@
+package json2yaml
+
+import (
+	"bytes"
+	"cmp"
+	"encoding"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Marshal returns the YAML encoding of v. The value is encoded in the same
+// manner as [json.Marshal], respecting the json struct tags, [json.Marshaler]
+// and [encoding.TextMarshaler], but written in YAML directly.
+func Marshal(v any, opts ...Option) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := NewEncoder(&buf, opts...).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Encoder writes the YAML encoding of values to an output stream.
+type Encoder struct {
+	c    *converter
+	opts []Option
+}
+
+// NewEncoder returns a new encoder that writes to w.
+func NewEncoder(w io.Writer, opts ...Option) *Encoder {
+	return &Encoder{newConverter(w, opts), opts}
+}
+
+// Encode writes the YAML encoding of v to the stream. Each value is written
+// as a document, separated by the document start marker (---). The value is
+// written while walking through it, so the output may be written partially on
+// failure, and the next value is written as a new document.
+func (enc *Encoder) Encode(v any) error {
+	err := enc.c.convertTokens(&valueEncoder{
+		root: reflect.ValueOf(v), invalidUTF8: enc.c.invalidUTF8,
+	})
+	if err != nil {
+		documents := enc.c.documents
+		enc.c.reset(enc.c.w, enc.opts)
+		enc.c.documents = documents
+	}
+	return err
+}
+
+// valueEncoder is a [TokenReader] of the value, following the encoder of
+// encoding/json. The value is walked lazily with the stack of the iterators
+// of the arrays and objects.
+type valueEncoder struct {
+	root        reflect.Value
+	started     bool
+	stack       []encodeFrame
+	tokens      []json.Token // tokens to be read
+	entered     []any        // pointers entered for the current value
+	err         error
+	invalidUTF8 InvalidUTF8Policy
+	ptrLevel    int
+	ptrSeen     map[any]struct{}
+}
+
+// encodeFrame is an iterator of an array, a map or a struct.
+type encodeFrame struct {
+	value   reflect.Value
+	index   int
+	length  int
+	entries []mapEntry // of a map
+	fields  []field    // of a struct
+	ptrs    []any      // pointers entered for the value, left at the end
+}
+
+type mapEntry struct {
+	key   string
+	value reflect.Value
+}
+
+func (e *valueEncoder) Token() (json.Token, error) {
+	if len(e.tokens) == 0 {
+		if err := e.next(); err != nil {
+			return nil, err
+		}
+	}
+	token := e.tokens[0]
+	e.tokens = e.tokens[1:]
+	return token, nil
+}
+
+func (e *valueEncoder) More() bool {
+	if len(e.tokens) == 0 && e.next() != nil {
+		return true // Token returns the error
+	}
+	return e.tokens[0] != json.Delim('}') && e.tokens[0] != json.Delim(']')
+}
+
+// next walks the value to the next token.
+func (e *valueEncoder) next() error {
+	if e.err == nil {
+		e.err = e.nextInternal()
+	}
+	return e.err
+}
+
+func (e *valueEncoder) nextInternal() error {
+	if len(e.stack) == 0 {
+		if e.started {
+			return io.EOF
+		}
+		e.started = true
+		return e.start(e.root, false)
+	}
+	f := &e.stack[len(e.stack)-1]
+	switch f.value.Kind() {
+	case reflect.Struct:
+		for f.index < len(f.fields) {
+			if fv, ok := f.field(); ok {
+				quoted := f.fields[f.index-1].quoted
+				e.tokens = append(e.tokens, f.fields[f.index-1].name)
+				return e.start(fv, quoted)
+			}
+		}
+		e.end(json.Delim('}'))
+	case reflect.Map:
+		if f.index < len(f.entries) {
+			entry := f.entries[f.index]
+			f.index++
+			e.tokens = append(e.tokens, entry.key)
+			return e.start(entry.value, false)
+		}
+		e.end(json.Delim('}'))
+	default:
+		if f.index < f.length {
+			v := f.value.Index(f.index)
+			f.index++
+			return e.start(v, false)
+		}
+		e.end(json.Delim(']'))
+	}
+	return nil
+}
+
+// start encodes the value, where the arrays and objects are pushed to the
+// stack, and leaves the pointers entered for the other values.
+func (e *valueEncoder) start(v reflect.Value, quoted bool) error {
+	if err := e.encode(v, quoted); err != nil {
+		return err
+	}
+	for i := len(e.entered) - 1; i >= 0; i-- {
+		e.leave(e.entered[i])
+	}
+	e.entered = e.entered[:0]
+	return nil
+}
+
+func (e *valueEncoder) push(f encodeFrame, delim json.Delim) {
+	f.ptrs, e.entered = e.entered, nil
+	e.stack = append(e.stack, f)
+	e.tokens = append(e.tokens, delim)
+}
+
+func (e *valueEncoder) end(delim json.Delim) {
+	f := e.stack[len(e.stack)-1]
+	e.stack = e.stack[:len(e.stack)-1]
+	for i := len(f.ptrs) - 1; i >= 0; i-- {
+		e.leave(f.ptrs[i])
+	}
+	e.tokens = append(e.tokens, delim)
+}
+
+var (
+	marshalerType     = reflect.TypeFor[json.Marshaler]()
+	textMarshalerType = reflect.TypeFor[encoding.TextMarshaler]()
+	isZeroerType      = reflect.TypeFor[isZeroer]()
+	numberType        = reflect.TypeFor[json.Number]()
+)
+
+type isZeroer interface {
+	IsZero() bool
+}
+
+// the depth to start detecting cycles, same as encoding/json
+const startDetectingCyclesAfter = 1000
+
+func (e *valueEncoder) encode(v reflect.Value, quoted bool) error {
+	if !v.IsValid() {
+		e.tokens = append(e.tokens, nil)
+		return nil
+	}
+	t := v.Type()
+	if t.Kind() != reflect.Pointer && v.CanAddr() {
+		if pt := reflect.PointerTo(t); pt.Implements(marshalerType) ||
+			pt.Implements(textMarshalerType) {
+			v, t = v.Addr(), pt
+		}
+	}
+	if t.Implements(marshalerType) {
+		return e.encodeMarshaler(v)
+	}
+	if t.Implements(textMarshalerType) {
+		return e.encodeTextMarshaler(v)
+	}
+	switch v.Kind() {
+	case reflect.Bool:
+		e.scalar(strconv.FormatBool(v.Bool()), quoted, v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		s := strconv.FormatInt(v.Int(), 10)
+		e.scalar(s, quoted, json.Number(s))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		s := strconv.FormatUint(v.Uint(), 10)
+		e.scalar(s, quoted, json.Number(s))
+	case reflect.Float32, reflect.Float64:
+		bits := t.Bits()
+		if f := v.Float(); math.IsInf(f, 0) || math.IsNaN(f) {
+			return &json.UnsupportedValueError{Value: v, Str: strconv.FormatFloat(f, 'g', -1, bits)}
+		}
+		s := formatFloat(v.Float(), bits)
+		e.scalar(s, quoted, json.Number(s))
+	case reflect.String:
+		if t == numberType {
+			s := v.String()
+			if s == "" {
+				s = "0"
+			}
+			if !numberPattern.MatchString(s) {
+				return fmt.Errorf("json: invalid number literal %q", s)
+			}
+			e.scalar(s, quoted, json.Number(s))
+			break
+		}
+		s, err := e.string(v)
+		if err != nil {
+			return err
+		}
+		if quoted {
+			bs, _ := json.Marshal(s)
+			s = string(bs)
+		}
+		e.tokens = append(e.tokens, s)
+	case reflect.Interface:
+		if v.IsNil() {
+			e.tokens = append(e.tokens, nil)
+			break
+		}
+		return e.encode(v.Elem(), false)
+	case reflect.Struct:
+		e.push(encodeFrame{value: v, fields: cachedTypeFields(t)}, json.Delim('{'))
+	case reflect.Map:
+		return e.encodeMap(v)
+	case reflect.Slice:
+		if v.IsNil() {
+			e.tokens = append(e.tokens, nil)
+			break
+		}
+		if et := t.Elem(); et.Kind() == reflect.Uint8 {
+			if pt := reflect.PointerTo(et); !pt.Implements(marshalerType) &&
+				!pt.Implements(textMarshalerType) {
+				e.tokens = append(e.tokens, base64.StdEncoding.EncodeToString(v.Bytes()))
+				break
+			}
+		}
+		if err := e.enter(v, struct {
+			ptr any
+			len int
+		}{v.UnsafePointer(), v.Len()}); err != nil {
+			return err
+		}
+		e.encodeArray(v)
+	case reflect.Array:
+		e.encodeArray(v)
+	case reflect.Pointer:
+		if v.IsNil() {
+			e.tokens = append(e.tokens, nil)
+			break
+		}
+		if err := e.enter(v, v.UnsafePointer()); err != nil {
+			return err
+		}
+		return e.encode(v.Elem(), quoted)
+	default:
+		return &json.UnsupportedTypeError{Type: t}
+	}
+	return nil
+}
+
+func (e *valueEncoder) scalar(s string, quoted bool, token json.Token) {
+	if quoted {
+		token = s
+	}
+	e.tokens = append(e.tokens, token)
+}
+
+// enter detects the cycles of the pointers, maps and slices. The pointer is
+// left by leave after encoding the value.
+func (e *valueEncoder) enter(v reflect.Value, ptr any) error {
+	if e.ptrLevel++; e.ptrLevel > startDetectingCyclesAfter {
+		if _, ok := e.ptrSeen[ptr]; ok {
+			return &json.UnsupportedValueError{
+				Value: v, Str: fmt.Sprintf("encountered a cycle via %s", v.Type()),
+			}
+		}
+		if e.ptrSeen == nil {
+			e.ptrSeen = make(map[any]struct{})
+		}
+		e.ptrSeen[ptr] = struct{}{}
+	}
+	e.entered = append(e.entered, ptr)
+	return nil
+}
+
+func (e *valueEncoder) leave(ptr any) {
+	if e.ptrLevel > startDetectingCyclesAfter {
+		delete(e.ptrSeen, ptr)
+	}
+	e.ptrLevel--
+}
+
+func (e *valueEncoder) string(v reflect.Value) (string, error) {
+	s := v.String()
+	if utf8.ValidString(s) {
+		return s, nil
+	}
+	switch e.invalidUTF8 {
+	case RejectInvalidUTF8:
+		return "", &json.UnsupportedValueError{Value: v, Str: strconv.Quote(s)}
+	case PreserveInvalidUTF8:
+		return s, nil
+	}
+	var sb strings.Builder
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		sb.WriteRune(r)
+		i += size
+	}
+	return sb.String(), nil
+}
+
+func (e *valueEncoder) encodeMarshaler(v reflect.Value) error {
+	if v.Kind() == reflect.Pointer && v.IsNil() {
+		e.tokens = append(e.tokens, nil)
+		return nil
+	}
+	m, ok := v.Interface().(json.Marshaler)
+	if !ok {
+		e.tokens = append(e.tokens, nil)
+		return nil
+	}
+	bs, err := m.MarshalJSON()
+	if err != nil {
+		return &json.MarshalerError{Type: v.Type(), Err: err}
+	}
+	dec := json.NewDecoder(bytes.NewReader(bs))
+	dec.UseNumber()
+	for depth := 0; ; {
+		token, err := dec.Token()
+		if err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return &json.MarshalerError{Type: v.Type(), Err: err}
+		}
+		e.tokens = append(e.tokens, token)
+		switch token {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+		if depth == 0 {
+			break
+		}
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		if err == nil {
+			err = errors.New("invalid character after top-level value")
+		}
+		return &json.MarshalerError{Type: v.Type(), Err: err}
+	}
+	return nil
+}
+
+func (e *valueEncoder) encodeTextMarshaler(v reflect.Value) error {
+	if v.Kind() == reflect.Pointer && v.IsNil() {
+		e.tokens = append(e.tokens, nil)
+		return nil
+	}
+	m, ok := v.Interface().(encoding.TextMarshaler)
+	if !ok {
+		e.tokens = append(e.tokens, nil)
+		return nil
+	}
+	bs, err := m.MarshalText()
+	if err != nil {
+		return &json.MarshalerError{Type: v.Type(), Err: err}
+	}
+	s, err := e.string(reflect.ValueOf(string(bs)))
+	if err != nil {
+		return err
+	}
+	e.tokens = append(e.tokens, s)
+	return nil
+}
+
+func (e *valueEncoder) encodeArray(v reflect.Value) {
+	e.push(encodeFrame{value: v, length: v.Len()}, json.Delim('['))
+}
+
+func (e *valueEncoder) encodeMap(v reflect.Value) error {
+	if v.IsNil() {
+		e.tokens = append(e.tokens, nil)
+		return nil
+	}
+	t := v.Type()
+	switch t.Key().Kind() {
+	case reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+	default:
+		if !t.Key().Implements(textMarshalerType) {
+			return &json.UnsupportedTypeError{Type: t}
+		}
+	}
+	if err := e.enter(v, v.UnsafePointer()); err != nil {
+		return err
+	}
+	entries := make([]mapEntry, 0, v.Len())
+	for iter := v.MapRange(); iter.Next(); {
+		key, err := e.resolveKey(iter.Key())
+		if err != nil {
+			return err
+		}
+		entries = append(entries, mapEntry{key, iter.Value()})
+	}
+	slices.SortFunc(entries, func(x, y mapEntry) int {
+		return strings.Compare(x.key, y.key)
+	})
+	e.push(encodeFrame{value: v, entries: entries}, json.Delim('{'))
+	return nil
+}
+
+func (e *valueEncoder) resolveKey(k reflect.Value) (string, error) {
+	if k.Kind() == reflect.String {
+		return e.string(k)
+	}
+	if tm, ok := k.Interface().(encoding.TextMarshaler); ok {
+		if k.Kind() == reflect.Pointer && k.IsNil() {
+			return "", nil
+		}
+		bs, err := tm.MarshalText()
+		if err != nil {
+			return "", &json.MarshalerError{Type: k.Type(), Err: err}
+		}
+		return e.string(reflect.ValueOf(string(bs)))
+	}
+	switch k.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(k.Int(), 10), nil
+	default:
+		return strconv.FormatUint(k.Uint(), 10), nil
+	}
+}
+
+// field returns the next field of the struct, and reports whether the field
+// is encoded.
+func (f *encodeFrame) field() (reflect.Value, bool) {
+	sf := f.fields[f.index]
+	f.index++
+	fv := f.value
+	for _, i := range sf.index {
+		if fv.Kind() == reflect.Pointer {
+			if fv.IsNil() {
+				return fv, false
+			}
+			fv = fv.Elem()
+		}
+		fv = fv.Field(i)
+	}
+	if sf.omitEmpty && isEmptyValue(fv) || sf.omitZero && isZeroValue(fv) {
+		return fv, false
+	}
+	return fv, true
+}
+
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64,
+		reflect.Interface, reflect.Pointer:
+		return v.IsZero()
+	}
+	return false
+}
+
+func isZeroValue(v reflect.Value) bool {
+	if z, ok := v.Interface().(isZeroer); ok {
+		if v.Kind() == reflect.Pointer && v.IsNil() {
+			return true
+		}
+		return z.IsZero()
+	}
+	if reflect.PointerTo(v.Type()).Implements(isZeroerType) {
+		if !v.CanAddr() {
+			// copy the value to call the method of the pointer receiver
+			pv := reflect.New(v.Type())
+			pv.Elem().Set(v)
+			v = pv.Elem()
+		}
+		return v.Addr().Interface().(isZeroer).IsZero()
+	}
+	return v.IsZero()
+}
+
+// field is a field of a struct to be encoded.
+type field struct {
+	name      string
+	tagged    bool
+	index     []int
+	typ       reflect.Type
+	omitEmpty bool
+	omitZero  bool
+	quoted    bool
+}
+
+var fieldCache sync.Map // map[reflect.Type][]field
+
+func cachedTypeFields(t reflect.Type) []field {
+	if fs, ok := fieldCache.Load(t); ok {
+		return fs.([]field)
+	}
+	fs, _ := fieldCache.LoadOrStore(t, typeFields(t))
+	return fs.([]field)
+}
+
+// typeFields returns the fields to be encoded, following the rules of
+// encoding/json for the embedded structs and the conflicting names.
+func typeFields(t reflect.Type) []field {
+	var fields []field
+	current, next := []field{}, []field{{typ: t}}
+	var count, nextCount map[reflect.Type]int
+	visited := map[reflect.Type]bool{}
+	for len(next) > 0 {
+		current, next = next, current[:0]
+		count, nextCount = nextCount, map[reflect.Type]int{}
+		for _, f := range current {
+			if visited[f.typ] {
+				continue
+			}
+			visited[f.typ] = true
+			for i := range f.typ.NumField() {
+				sf := f.typ.Field(i)
+				if sf.Anonymous {
+					t := sf.Type
+					if t.Kind() == reflect.Pointer {
+						t = t.Elem()
+					}
+					if !sf.IsExported() && t.Kind() != reflect.Struct {
+						continue
+					}
+				} else if !sf.IsExported() {
+					continue
+				}
+				tag := sf.Tag.Get("json")
+				if tag == "-" {
+					continue
+				}
+				name, opts, _ := strings.Cut(tag, ",")
+				if !isValidTag(name) {
+					name = ""
+				}
+				index := append(slices.Clip(f.index), i)
+				ft := sf.Type
+				if ft.Name() == "" && ft.Kind() == reflect.Pointer {
+					ft = ft.Elem()
+				}
+				if name != "" || !sf.Anonymous || ft.Kind() != reflect.Struct {
+					var quoted bool
+					if hasTagOption(opts, "string") {
+						switch ft.Kind() {
+						case reflect.Bool,
+							reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+							reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+							reflect.Float32, reflect.Float64, reflect.String:
+							quoted = true
+						}
+					}
+					tagged := name != ""
+					if name == "" {
+						name = sf.Name
+					}
+					fields = append(fields, field{
+						name: name, tagged: tagged, index: index, typ: ft,
+						omitEmpty: hasTagOption(opts, "omitempty"),
+						omitZero:  hasTagOption(opts, "omitzero"),
+						quoted:    quoted,
+					})
+					if count[f.typ] > 1 {
+						// annihilate the fields of the multiple embedded structs
+						fields = append(fields, fields[len(fields)-1])
+					}
+					continue
+				}
+				if nextCount[ft]++; nextCount[ft] == 1 {
+					next = append(next, field{name: ft.Name(), index: index, typ: ft})
+				}
+			}
+		}
+	}
+	slices.SortFunc(fields, func(x, y field) int {
+		if c := strings.Compare(x.name, y.name); c != 0 {
+			return c
+		}
+		if c := cmp.Compare(len(x.index), len(y.index)); c != 0 {
+			return c
+		}
+		if x.tagged != y.tagged {
+			if x.tagged {
+				return -1
+			}
+			return 1
+		}
+		return slices.Compare(x.index, y.index)
+	})
+	// remove the fields with the conflicting names, keeping the dominant ones
+	out := fields[:0]
+	for i, j := 0, 0; i < len(fields); i = j {
+		for j = i + 1; j < len(fields) && fields[j].name == fields[i].name; j++ {
+		}
+		if j-i > 1 && len(fields[i].index) == len(fields[i+1].index) &&
+			fields[i].tagged == fields[i+1].tagged {
+			continue
+		}
+		out = append(out, fields[i])
+	}
+	slices.SortFunc(out, func(x, y field) int {
+		return slices.Compare(x.index, y.index)
+	})
+	return out
+}
+
+func isValidTag(s string) bool {
+	for _, c := range s {
+		if !strings.ContainsRune("!#$%&()*+-./:;<=>?@[]^_{|}~ ", c) &&
+			!unicode.IsLetter(c) && !unicode.IsDigit(c) {
+			return false
+		}
+	}
+	return s != ""
+}
+
+func hasTagOption(opts, name string) bool {
+	for opt := range strings.SplitSeq(opts, ",") {
+		if opt == name {
+			return true
+		}
+	}
+	return false
+}
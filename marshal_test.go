@@ -0,0 +1,505 @@
+package json2yaml_test
+
+import (
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/itchyny/json2yaml"
+)
+
+type marshalEmbedded struct {
+	A int    `json:"a"`
+	B string `json:"b,omitempty"`
+}
+
+type marshalConflict struct {
+	B string `json:"b"`
+	C string
+}
+
+type marshalStruct struct {
+	*marshalEmbedded
+	marshalConflict
+	Name     string            `json:"name"`
+	Tags     []string          `json:"tags,omitempty"`
+	Count    int64             `json:"count,string"`
+	Ratio    float32           `json:"ratio"`
+	Enabled  bool              `json:",omitempty"`
+	Time     time.Time         `json:"time,omitzero"`
+	Raw      json.RawMessage   `json:"raw"`
+	Number   json.Number       `json:"number"`
+	Bytes    []byte            `json:"bytes"`
+	IP       net.IP            `json:"ip"`
+	Map      map[string]any    `json:"map"`
+	IntMap   map[int]bool      `json:"int_map"`
+	Pointer  *marshalEmbedded  `json:"pointer"`
+	Array    [2]uint8          `json:"array"`
+	Ignored  string            `json:"-"`
+	Dash     string            `json:"-,"`
+	Empty    map[string]string `json:"empty,omitempty"`
+	private  string
+	Multi    string `json:"multi"`
+	Interval duration
+}
+
+type duration time.Duration
+
+func (d duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+type marshalerError struct{}
+
+func (marshalerError) MarshalJSON() ([]byte, error) {
+	return nil, errors.New("marshaler error")
+}
+
+type marshalerInvalid struct{}
+
+func (marshalerInvalid) MarshalJSON() ([]byte, error) {
+	return []byte(`{"a": 1} 2`), nil
+}
+
+type marshalerEmpty struct{}
+
+func (marshalerEmpty) MarshalJSON() ([]byte, error) {
+	return nil, nil
+}
+
+type marshalKey [2]int
+
+func (k marshalKey) MarshalText() ([]byte, error) {
+	if k[0] < 0 {
+		return nil, errors.New("negative key")
+	}
+	return fmt.Appendf(nil, "%d-%d", k[0], k[1]), nil
+}
+
+type marshalPointerText struct {
+	s string
+}
+
+func (p *marshalPointerText) MarshalText() ([]byte, error) {
+	return []byte(p.s), nil
+}
+
+type marshalZero struct {
+	N int `json:"n"`
+}
+
+func (z *marshalZero) IsZero() bool {
+	return z.N < 0
+}
+
+type marshalOmit struct {
+	Time          *time.Time             `json:"time,omitzero"`
+	Zero          marshalZero            `json:"zero,omitzero"`
+	Negative      marshalZero            `json:"negative,omitzero"`
+	Int           int                    `json:"int,omitzero"`
+	Struct        struct{}               `json:"struct,omitempty"`
+	Slice         []int                  `json:"slice"`
+	Map           map[string]int         `json:"map"`
+	Number        json.Number            `json:"number"`
+	String        string                 `json:"string,string"`
+	Text          marshalPointerText     `json:"text"`
+	NilText       *duration              `json:"nil_text"`
+	Marshaler     json.Marshaler         `json:"marshaler"`
+	TextMarshaler encoding.TextMarshaler `json:"text_marshaler"`
+	NilMarshaler  *marshalerInvalid      `json:"nil_marshaler"`
+	Keys          map[marshalKey]int     `json:"keys"`
+	PointerKeys   map[*marshalKey]int    `json:"pointer_keys"`
+	UintKeys      map[uint16]int         `json:"uint_keys"`
+}
+
+type marshalBase struct {
+	B int
+}
+
+type marshalLeft struct {
+	marshalBase
+	A int
+	F int `json:"G"`
+}
+
+type marshalRight struct {
+	marshalBase
+	C int
+}
+
+type marshalNested struct {
+	marshalLeft
+	D int
+}
+
+type marshalTagged struct {
+	E int `json:"D"`
+	G int
+}
+
+type marshalInt int
+
+type marshalEmbeds struct {
+	*marshalEmbedded
+	marshalLeft
+	marshalRight
+	marshalNested
+	marshalTagged
+	marshalInt
+	C string
+}
+
+type cycle struct {
+	Next *cycle `json:"next"`
+}
+
+func TestMarshal(t *testing.T) {
+	c := &cycle{}
+	c.Next = c
+	d, deep := &cycle{}, ""
+	for i := range 1100 {
+		d, deep = &cycle{d}, deep+strings.Repeat("  ", i)+"next:\n"
+	}
+	s := []any{nil}
+	s[0] = s
+	m := map[string]any{}
+	m["a"] = m
+	n := 1
+	testCases := []struct {
+		name  string
+		value any
+		want  string
+		err   string
+	}{
+		{
+			name:  "nil",
+			value: nil,
+			want:  "null\n",
+		},
+		{
+			name:  "scalars",
+			value: []any{true, 0, -1, uint8(255), 3.14, 1e21, 1e-7, float32(0.1), "", "null", "foo", "a\nb\n"},
+			want: `- true
+- 0
+- -1
+- 255
+- 3.14
+- 1e+21
+- 1e-7
+- 0.1
+- ""
+- "null"
+- foo
+- |
+  a
+  b
+`,
+		},
+		{
+			name: "struct",
+			value: marshalStruct{
+				marshalEmbedded: &marshalEmbedded{A: 1},
+				marshalConflict: marshalConflict{B: "x", C: "y"},
+				Name:            "test",
+				Count:           42,
+				Ratio:           0.5,
+				Raw:             json.RawMessage(`{"x": [1, 2.50]}`),
+				Number:          "1e100",
+				Bytes:           []byte("hello"),
+				IP:              net.IPv4(127, 0, 0, 1),
+				Map:             map[string]any{"z": 1, "a": []int{}, "m": nil},
+				IntMap:          map[int]bool{10: true, 2: false},
+				Array:           [2]uint8{1, 2},
+				Ignored:         "ignored",
+				Dash:            "dash",
+				private:         "private",
+				Multi:           "a\nb",
+				Interval:        duration(90 * time.Second),
+			},
+			want: `a: 1
+C: "y"
+name: test
+count: "42"
+ratio: 0.5
+raw:
+  x:
+    - 1
+    - 2.50
+number: 1e100
+bytes: aGVsbG8=
+ip: 127.0.0.1
+map:
+  a: []
+  m: null
+  z: 1
+int_map:
+  "10": true
+  "2": false
+pointer: null
+array:
+  - 1
+  - 2
+"-": dash
+multi: |-
+  a
+  b
+Interval: 1m30s
+`,
+		},
+		{
+			name: "omitzero and marshalers",
+			value: &marshalOmit{
+				Negative:    marshalZero{-1},
+				String:      "foo",
+				Text:        marshalPointerText{"text"},
+				Keys:        map[marshalKey]int{{1, 2}: 1, {0, 3}: 2},
+				PointerKeys: map[*marshalKey]int{nil: 1},
+				UintKeys:    map[uint16]int{10: 1, 9: 2},
+			},
+			want: `zero:
+  "n": 0
+struct: {}
+slice: null
+map: null
+number: 0
+string: "\"foo\""
+text: text
+nil_text: null
+marshaler: null
+text_marshaler: null
+nil_marshaler: null
+keys:
+  0-3: 2
+  1-2: 1
+pointer_keys:
+  "": 1
+uint_keys:
+  "10": 1
+  "9": 2
+`,
+		},
+		{
+			name:  "omitzero of non-addressable value",
+			value: marshalOmit{Zero: marshalZero{1}, Negative: marshalZero{-1}, Int: 1},
+			want: `zero:
+  "n": 1
+int: 1
+struct: {}
+slice: null
+map: null
+number: 0
+string: "\"\""
+text: {}
+nil_text: null
+marshaler: null
+text_marshaler: null
+nil_marshaler: null
+keys: null
+pointer_keys: null
+uint_keys: null
+`,
+		},
+		{
+			name:  "embedded structs",
+			value: marshalEmbeds{marshalLeft: marshalLeft{A: 1, F: 3}, marshalTagged: marshalTagged{E: 2, G: 4}, C: "c"},
+			want:  "A: 1\nG: 3\nD: 2\nC: c\n",
+		},
+		{
+			name:  "invalid utf-8",
+			value: "\xff",
+			want:  "�\n",
+		},
+		{
+			name:  "unsupported type",
+			value: map[string]any{"a": make(chan int)},
+			err:   "json: unsupported type: chan int",
+		},
+		{
+			name:  "unsupported value",
+			value: []float64{math.NaN()},
+			err:   "json: unsupported value: NaN",
+		},
+		{
+			name:  "cycle",
+			value: c,
+			err:   "json: unsupported value: encountered a cycle via *json2yaml_test.cycle",
+		},
+		{
+			name:  "cycle of slices",
+			value: s,
+			err:   "json: unsupported value: encountered a cycle via []interface {}",
+		},
+		{
+			name:  "cycle of maps",
+			value: m,
+			err:   "json: unsupported value: encountered a cycle via map[string]interface {}",
+		},
+		{
+			name:  "deep pointers",
+			value: d,
+			want:  deep + strings.Repeat("  ", 1100) + "next: null\n",
+		},
+		{
+			name:  "pointers to scalar",
+			value: []any{&n, map[string]*int{"a": &n}},
+			want:  "- 1\n- a: 1\n",
+		},
+		{
+			name:  "marshaler error",
+			value: []any{marshalerError{}},
+			err:   "json: error calling MarshalJSON for type json2yaml_test.marshalerError: marshaler error",
+		},
+		{
+			name:  "invalid marshaler output",
+			value: marshalerInvalid{},
+			err:   "json: error calling MarshalJSON for type json2yaml_test.marshalerInvalid: invalid character after top-level value",
+		},
+		{
+			name:  "empty marshaler output",
+			value: marshalerEmpty{},
+			err:   "json: error calling MarshalJSON for type json2yaml_test.marshalerEmpty: unexpected EOF",
+		},
+		{
+			name:  "text marshaler error",
+			value: []any{marshalKey{-1, 0}},
+			err:   "json: error calling MarshalJSON for type json2yaml_test.marshalKey: negative key",
+		},
+		{
+			name:  "text marshaler error of key",
+			value: map[marshalKey]int{{-1, 0}: 1},
+			err:   "json: error calling MarshalJSON for type json2yaml_test.marshalKey: negative key",
+		},
+		{
+			name:  "unsupported key type",
+			value: map[[2]int]int{},
+			err:   "json: unsupported type: map[[2]int]int",
+		},
+		{
+			name:  "invalid number",
+			value: json.Number("0x1"),
+			err:   `json: invalid number literal "0x1"`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := json2yaml.Marshal(tc.value)
+			if tc.err == "" {
+				if err != nil {
+					t.Fatalf("should not raise an error but got: %s", err)
+				}
+				if got, want := diff(string(got), tc.want); got != want {
+					t.Fatalf("should write\n  %q\nbut got\n  %q", want, got)
+				}
+				bs, err := json.Marshal(tc.value)
+				if err != nil {
+					t.Fatal(err)
+				}
+				var sb strings.Builder
+				if err := json2yaml.Convert(&sb, strings.NewReader(string(bs))); err != nil {
+					t.Fatal(err)
+				}
+				if got, want := diff(sb.String(), tc.want); got != want {
+					t.Fatalf("should be consistent with Convert\n  %q\nbut got\n  %q", want, got)
+				}
+			} else {
+				if err == nil {
+					t.Fatalf("should raise an error %q but got no error", tc.err)
+				}
+				if err.Error() != tc.err {
+					t.Fatalf("should raise an error %q but got error %q", tc.err, err)
+				}
+			}
+		})
+	}
+}
+
+func TestEncoder(t *testing.T) {
+	var sb strings.Builder
+	enc := json2yaml.NewEncoder(&sb, json2yaml.WithHeaderComment("header"))
+	for _, v := range []any{map[string]int{"a": 1}, []string{"b"}, nil} {
+		if err := enc.Encode(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, v := range []any{make(chan int), []any{"c", make(chan int)}} {
+		if err := enc.Encode(v); err == nil {
+			t.Fatal("should raise an error")
+		}
+	}
+	if err := enc.Encode("d"); err != nil {
+		t.Fatal(err)
+	}
+	want := "# header\na: 1\n---\n- b\n---\nnull\n---\n- c\n- \n---\nd\n"
+	if got, want := diff(sb.String(), want); got != want {
+		t.Fatalf("should write\n  %q\nbut got\n  %q", want, got)
+	}
+}
+
+func TestMarshalInvalidTag(t *testing.T) {
+	// encoding/json v2 omits the fields of invalid names, unlike v1
+	got, err := json2yaml.Marshal(struct {
+		A int `json:"a'b"`
+		B int `json:"b,omitempty"`
+	}{1, 2})
+	if err != nil {
+		t.Fatalf("should not raise an error but got: %s", err)
+	}
+	if got, want := string(got), "A: 1\nb: 2\n"; got != want {
+		t.Fatalf("should write\n  %q\nbut got\n  %q", want, got)
+	}
+}
+
+func TestMarshalInvalidUTF8(t *testing.T) {
+	testCases := []struct {
+		name   string
+		value  any
+		policy json2yaml.InvalidUTF8Policy
+		want   string
+		err    string
+	}{
+		{
+			name:   "preserve",
+			value:  []any{"a\xffb", &marshalPointerText{"\xfe"}},
+			policy: json2yaml.PreserveInvalidUTF8,
+			want:   "- \"a\\xFFb\"\n- \"\\xFE\"\n",
+		},
+		{
+			name:   "reject string",
+			value:  []any{"a\xffb"},
+			policy: json2yaml.RejectInvalidUTF8,
+			err:    `json: unsupported value: "a\xffb"`,
+		},
+		{
+			name:   "reject text marshaler",
+			value:  &marshalPointerText{"\xfe"},
+			policy: json2yaml.RejectInvalidUTF8,
+			err:    `json: unsupported value: "\xfe"`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := json2yaml.Marshal(tc.value, json2yaml.WithInvalidUTF8(tc.policy))
+			if tc.err == "" {
+				if err != nil {
+					t.Fatalf("should not raise an error but got: %s", err)
+				}
+				if got, want := diff(string(got), tc.want); got != want {
+					t.Fatalf("should write\n  %q\nbut got\n  %q", want, got)
+				}
+			} else {
+				if err == nil {
+					t.Fatalf("should raise an error %q but got no error", tc.err)
+				}
+				if err.Error() != tc.err {
+					t.Fatalf("should raise an error %q but got error %q", tc.err, err)
+				}
+			}
+		})
+	}
+}
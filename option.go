@@ -0,0 +1,259 @@
+package json2yaml
+
+import "regexp"
+
+// Option is an option for [Convert].
+type Option func(*converter)
+
+// InvalidUTF8Policy is a policy to handle invalid UTF-8 sequences in strings.
+type InvalidUTF8Policy int
+
+const (
+	// ReplaceInvalidUTF8 replaces invalid bytes with U+FFFD (the default).
+	ReplaceInvalidUTF8 InvalidUTF8Policy = iota
+	// RejectInvalidUTF8 returns an [*EncodingError] with the byte offset.
+	RejectInvalidUTF8
+	// PreserveInvalidUTF8 writes invalid bytes as \xNN escape sequences in
+	// double-quoted strings. This is not lossless, because YAML parsers read
+	// \xNN as the character U+00NN instead of the byte.
+	PreserveInvalidUTF8
+)
+
+// WithInvalidUTF8 sets the policy to handle invalid UTF-8 in strings.
+func WithInvalidUTF8(policy InvalidUTF8Policy) Option {
+	return func(c *converter) {
+		c.invalidUTF8 = policy
+	}
+}
+
+// WithASCII makes the output consist of only ASCII characters. The strings
+// containing non-ASCII characters, including mapping keys, are double-quoted
+// and escaped with \uXXXX or \UXXXXXXXX.
+func WithASCII() Option {
+	return func(c *converter) {
+		c.ascii = true
+	}
+}
+
+// NumberFormat is a format of numbers.
+type NumberFormat int
+
+const (
+	// VerbatimNumbers writes numbers as in the input (the default).
+	VerbatimNumbers NumberFormat = iota
+	// CanonicalNumbers writes numbers as JSON.stringify of ECMAScript does
+	// (e.g. 1E+9 as 1000000000, and 1.0 as 1).
+	CanonicalNumbers
+	// SafeNumbers quotes the integers out of the range where IEEE 754
+	// double-precision numbers can represent exactly (±(2^53-1)),
+	// so that the parsers do not lose the precision.
+	SafeNumbers
+)
+
+// WithNumberFormat sets the format of numbers.
+func WithNumberFormat(format NumberFormat) Option {
+	return func(c *converter) {
+		c.numberFormat = format
+	}
+}
+
+// WithStringTags makes the strings which would be read as other types written
+// with the !!str tag (e.g. !!str 0664) instead of double-quoting them.
+func WithStringTags() Option {
+	return func(c *converter) {
+		c.stringTags = true
+	}
+}
+
+// WithProfile sets the profile of quoting strings. The strings which the parser
+// of the profile reads as other types are quoted, while the other strings are
+// written as plain scalars as possible. Note that [WithVerification] reads the
+// output with the core schema of YAML 1.2, and the conversion fails when it is
+// used with the profiles other than [DefaultProfile] and [CoreSchemaProfile].
+func WithProfile(profile Profile) Option {
+	return func(c *converter) {
+		c.profile = profile
+	}
+}
+
+// WithNumberTags makes the integers out of the 64-bit range written with the
+// !!int tag, and the numbers overflowing the IEEE 754 double-precision format
+// written with the !!float tag.
+func WithNumberTags() Option {
+	return func(c *converter) {
+		c.numberTags = true
+	}
+}
+
+// WithBinaryKeys makes the base64 encoded strings of the mapping values, whose
+// keys match the pattern, written as !!binary block scalars wrapped at 76
+// columns.
+func WithBinaryKeys(pattern *regexp.Regexp) Option {
+	return func(c *converter) {
+		c.binaryKeys = pattern
+	}
+}
+
+// WithBinaryDetection makes the long base64 encoded strings, which decode to
+// binary data rather than text, written as !!binary block scalars.
+func WithBinaryDetection() Option {
+	return func(c *converter) {
+		c.binaryDetect = true
+	}
+}
+
+// WithBinarySchema makes the base64 encoded strings, whose contentEncoding is
+// base64 in the schema, written as !!binary block scalars.
+func WithBinarySchema(s *Schema) Option {
+	return func(c *converter) {
+		c.binarySchema = &schemaTracker{root: s.root}
+	}
+}
+
+// WithDocumentStart makes every document start with the marker (---),
+// including the first document.
+func WithDocumentStart() Option {
+	return func(c *converter) {
+		c.docStart = true
+	}
+}
+
+// WithDocumentEnd makes every document end with the marker (...).
+func WithDocumentEnd() Option {
+	return func(c *converter) {
+		c.docEnd = true
+	}
+}
+
+// WithYAMLDirective writes the %YAML directive of the version (e.g. 1.2)
+// before the first document.
+func WithYAMLDirective(version string) Option {
+	return func(c *converter) {
+		c.directives = append(c.directives, "%YAML "+version)
+	}
+}
+
+// WithTagDirective writes the %TAG directive, which associates the handle
+// (e.g. !e!) with the prefix, before the first document.
+func WithTagDirective(handle, prefix string) Option {
+	return func(c *converter) {
+		c.directives = append(c.directives, "%TAG "+handle+" "+prefix)
+	}
+}
+
+// WithHeaderComment writes the text as comment lines at the beginning of the
+// output (e.g. Code generated by json2yaml from foo.json. DO NOT EDIT.).
+func WithHeaderComment(text string) Option {
+	return func(c *converter) {
+		c.header = text
+	}
+}
+
+// WithSourceComment writes the comment of the source name (# Source: name) at
+// the beginning of each document.
+func WithSourceComment(name string) Option {
+	return func(c *converter) {
+		c.source = name
+	}
+}
+
+// WithSchemaComments writes the descriptions of the properties in the schema
+// as comments above the mapping keys.
+func WithSchemaComments(s *Schema) Option {
+	return func(c *converter) {
+		c.comments = &schemaTracker{root: s.root}
+	}
+}
+
+// WithSchemaValidation validates the JSON against the schema while converting.
+// The violations are returned as [*ValidationError] joined by [errors.Join]
+// after writing the entire YAML. The conversion stops with
+// [ErrValidationErrorLimit] on too many violations, and with
+// [ErrValidationCaptureLimit] on a large value checked against anyOf, oneOf,
+// not, enum or const.
+func WithSchemaValidation(s *Schema) Option {
+	return func(c *converter) {
+		c.validator = newValidator(s.root)
+	}
+}
+
+// WithVerification parses each document of the output back, and returns a
+// [*VerificationError] on the first value which differs from the input,
+// including the key order and the number representation. The numbers and
+// strings are compared as converted by [WithNumberFormat] and
+// [WithInvalidUTF8]. The output is buffered for each document, and the
+// document failing the verification is not written.
+func WithVerification() Option {
+	return func(c *converter) {
+		c.verifier = &verifier{}
+	}
+}
+
+// WithMaxDepth limits the nesting depth of arrays and objects, and the
+// conversion fails with [ErrDepthLimit] on exceeding the limit. The limits of
+// the conversion are disabled by zero or negative values.
+func WithMaxDepth(depth int) Option {
+	return func(c *converter) {
+		c.limits.depth = depth
+	}
+}
+
+// WithMaxDocuments limits the number of documents, and the conversion fails
+// with [ErrDocumentLimit] on exceeding the limit.
+func WithMaxDocuments(count int) Option {
+	return func(c *converter) {
+		c.limits.documents = count
+	}
+}
+
+// WithMaxStringLength limits the length of strings in bytes, including the
+// object keys, and the conversion fails with [ErrStringLimit] on exceeding
+// the limit. The strings in the input are rejected before decoding, when they
+// exceed six times the limit (the length of \uXXXX for one byte).
+func WithMaxStringLength(length int) Option {
+	return func(c *converter) {
+		c.limits.stringLength = length
+	}
+}
+
+// WithMaxOutputSize limits the size of the output in bytes, and the conversion
+// fails with [ErrSizeLimit] on exceeding the limit. The output is truncated
+// at the last line within the limit.
+func WithMaxOutputSize(size int64) Option {
+	return func(c *converter) {
+		c.limits.outputSize = size
+	}
+}
+
+// FlushPolicy is a policy to flush the buffered output to the writer.
+type FlushPolicy int
+
+const (
+	// FlushBySize flushes the output when the buffer exceeds the size set by
+	// [WithBufferSize] (the default).
+	FlushBySize FlushPolicy = iota
+	// FlushByDocument flushes the output at the end of each document, and
+	// when the buffer exceeds the size.
+	FlushByDocument
+	// FlushByLine flushes each line of the output on completion.
+	FlushByLine
+	// FlushAtEnd flushes the output only at the end of the conversion.
+	FlushAtEnd
+)
+
+// WithFlushPolicy sets the policy to flush the output.
+func WithFlushPolicy(policy FlushPolicy) Option {
+	return func(c *converter) {
+		c.flushPolicy = policy
+	}
+}
+
+// WithBufferSize sets the size of the buffer to flush the output by
+// [FlushBySize] and [FlushByDocument] (4 KiB by default).
+func WithBufferSize(size int) Option {
+	return func(c *converter) {
+		if size > 0 {
+			c.bufferSize = size
+		}
+	}
+}
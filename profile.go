@@ -0,0 +1,127 @@
+package json2yaml
+
+import "regexp"
+
+// Profile is a set of rules to quote the strings, which depends on the parser
+// reading the output.
+type Profile int
+
+const (
+	// DefaultProfile quotes the strings which any of the common parsers may
+	// read as other types (the default).
+	DefaultProfile Profile = iota
+	// CoreSchemaProfile quotes the strings resolved to other types by the core
+	// schema of YAML 1.2, including the yaml2json package.
+	CoreSchemaProfile
+	// PyYAMLProfile quotes the strings for PyYAML (Python).
+	PyYAMLProfile
+	// GoYAMLV2Profile quotes the strings for gopkg.in/yaml.v2 (Go).
+	GoYAMLV2Profile
+	// GoYAMLV3Profile quotes the strings for gopkg.in/yaml.v3 (Go).
+	GoYAMLV3Profile
+	// SnakeYAMLProfile quotes the strings for SnakeYAML (Java).
+	SnakeYAMLProfile
+	// PsychProfile quotes the strings for Psych (Ruby).
+	PsychProfile
+)
+
+// implicit types of plain scalars for each parser, matched against the entire
+// string; these patterns follow the resolvers of the parsers
+const (
+	coreSchemaImplicitTypes = `(?:` +
+		`|~|null|Null|NULL` +
+		`|true|True|TRUE|false|False|FALSE` +
+		`|[-+]?[0-9]+|0o[0-7]+|0x[0-9a-fA-F]+` +
+		`|[-+]?(?:\.[0-9]+|[0-9]+(?:\.[0-9]*)?)(?:[eE][-+]?[0-9]+)?` +
+		`|[-+]?\.(?:inf|Inf|INF)|\.(?:nan|NaN|NAN)` +
+		`)`
+	pyYAMLImplicitTypes = `(?:` +
+		`|~|null|Null|NULL` +
+		`|yes|Yes|YES|no|No|NO|true|True|TRUE|false|False|FALSE|on|On|ON|off|Off|OFF` +
+		`|[-+]?0b[0-1_]+|[-+]?0[0-7_]+|[-+]?(?:0|[1-9][0-9_]*)|[-+]?0x[0-9a-fA-F_]+` +
+		`|[-+]?[1-9][0-9_]*(?::[0-5]?[0-9])+` +
+		`|[-+]?[0-9][0-9_]*\.[0-9_]*(?:[eE][-+][0-9]+)?|\.[0-9_]+(?:[eE][-+][0-9]+)?` +
+		`|[-+]?[0-9][0-9_]*(?::[0-5]?[0-9])+\.[0-9_]*` +
+		`|[-+]?\.(?:inf|Inf|INF)|\.(?:nan|NaN|NAN)` +
+		`|` + yaml11Timestamp +
+		`|<<|=` +
+		`)`
+	snakeYAMLImplicitTypes = `(?:` +
+		`|~|null|Null|NULL` +
+		`|yes|Yes|YES|no|No|NO|true|True|TRUE|false|False|FALSE|on|On|ON|off|Off|OFF` +
+		`|[-+]?0b_*[0-1]+[0-1_]*|[-+]?0_*[0-7]+[0-7_]*|[-+]?(?:0|[1-9][0-9_]*)` +
+		`|[-+]?0x_*[0-9a-fA-F]+[0-9a-fA-F_]*|[-+]?[1-9][0-9_]*(?::[0-5]?[0-9])+` +
+		`|[-+]?(?:\.[0-9]+|[0-9_]+(?:\.[0-9_]*)?)(?:[eE][-+]?[0-9]+)?` +
+		`|[-+]?[0-9][0-9_]*(?::[0-5]?[0-9])+\.[0-9_]*` +
+		`|[-+]?\.(?:inf|Inf|INF)|\.(?:nan|NaN|NAN)` +
+		`|` + yaml11Timestamp +
+		`|<<|=` +
+		`)`
+	yaml11Timestamp = `[0-9][0-9][0-9][0-9]-[0-9][0-9]-[0-9][0-9]` +
+		`|[0-9][0-9][0-9][0-9]-[0-9][0-9]?-[0-9][0-9]?` +
+		`(?:[Tt]|[ \t]+)[0-9][0-9]?:[0-9][0-9]:[0-9][0-9](?:\.[0-9]*)?` +
+		`(?:[ \t]*(?:Z|[-+][0-9][0-9]?(?::[0-9][0-9])?))?`
+	// go-yaml removes underscores from the numbers, and parses the integers
+	// with the base prefix of Go (0b, 0o, 0x and 0 for octal)
+	goYAMLNumber = `[-+]?_*0_*[bBoOxX][0-9a-fA-F_]+` +
+		`|(?:[-+]_*)?(?:\.[0-9_]*[0-9][0-9_]*|[0-9][0-9_]*(?:\.[0-9_]*)?)` +
+		`(?:[eE]_*[-+]?_*[0-9][0-9_]*)?`
+	goYAMLTimestamp = `[0-9]{4}-[0-9]{1,2}-[0-9]{1,2}` +
+		`(?:[Tt ][0-9]{1,2}:[0-9]{1,2}:[0-9]{1,2}(?:\.[0-9]*)?(?:Z|[-+][0-9]{2}:[0-9]{2})?)?`
+	goYAMLV2ImplicitTypes = `(?:` +
+		`|~|null|Null|NULL` +
+		`|y|Y|yes|Yes|YES|n|N|no|No|NO|true|True|TRUE|false|False|FALSE|on|On|ON|off|Off|OFF` +
+		`|[-+]?\.(?:inf|Inf|INF)|\.(?:nan|NaN|NAN)` +
+		`|` + goYAMLNumber +
+		`|` + goYAMLTimestamp +
+		`|<<` +
+		`)`
+	goYAMLV3ImplicitTypes = `(?:` +
+		`|~|null|Null|NULL` +
+		`|true|True|TRUE|false|False|FALSE` +
+		`|[-+]?\.(?:inf|Inf|INF)|\.(?:nan|NaN|NAN)` +
+		`|` + goYAMLNumber +
+		`|` + goYAMLTimestamp +
+		`|<<` +
+		`)`
+	// Psych reads the strings starting with a colon as symbols, and allows
+	// commas in the numbers
+	psychImplicitTypes = `(?:` +
+		`|~|(?i:null)` +
+		`|(?i:yes|true|on|no|false|off)` +
+		`|(?i:[-+]?\.inf|\.nan)` +
+		`|:.+` +
+		`|[-+]?0b[0-1_,]+|[-+]?0[0-7_,]+|[-+]?0x[0-9a-fA-F_,]+` +
+		`|[-+]?[0-9][0-9_,]*(?:\.[0-9_]*)?(?:[eE][-+]?[0-9]+)?` +
+		`|[-+]?(?:[0-9][0-9_,]*)?\.[0-9]*(?:[eE][-+][0-9]+)?` +
+		`|[-+]?[0-9][0-9_]*(?::[0-5]?[0-9]){1,2}(?:\.[0-9_]*)?` +
+		`|[0-9]{4}-[0-9]{1,2}-[0-9]{1,2}` +
+		`(?:(?:[Tt]|\s+)[0-9]{1,2}:[0-9]{2}:[0-9]{2}(?:\.[0-9]*)?` +
+		`(?:\s*(?:Z|[-+][0-9]{1,2}:?(?:[0-9]{2})?))?)?` +
+		`|<<` +
+		`)`
+)
+
+var profileQuotePatterns = []*regexp.Regexp{
+	DefaultProfile:    quoteSingleLineStringPattern,
+	CoreSchemaProfile: newQuoteSingleLineStringPattern(coreSchemaImplicitTypes),
+	PyYAMLProfile:     newQuoteSingleLineStringPattern(pyYAMLImplicitTypes),
+	GoYAMLV2Profile:   newQuoteSingleLineStringPattern(goYAMLV2ImplicitTypes),
+	GoYAMLV3Profile:   newQuoteSingleLineStringPattern(goYAMLV3ImplicitTypes),
+	SnakeYAMLProfile:  newQuoteSingleLineStringPattern(snakeYAMLImplicitTypes),
+	PsychProfile:      newQuoteSingleLineStringPattern(psychImplicitTypes),
+}
+
+func newQuoteSingleLineStringPattern(implicitTypes string) *regexp.Regexp {
+	return regexp.MustCompile(
+		`^(?:` + implicitTypes + `$|` + quoteLeadingIndicators + `)|` + quoteIndicators,
+	)
+}
+
+// quotePattern returns the pattern of the single-line strings to be quoted.
+func (p Profile) quotePattern() *regexp.Regexp {
+	if p < 0 || int(p) >= len(profileQuotePatterns) {
+		p = DefaultProfile
+	}
+	return profileQuotePatterns[p]
+}
@@ -0,0 +1,105 @@
+package json2yaml_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/itchyny/json2yaml"
+)
+
+func TestConvertProfile(t *testing.T) {
+	const src = `["yes", "y", "On", "tRUE", "NULL", "nUll", "0o17", "017", "08",
+		"1_000", "+_1", "1e3", "1.0e+3", ".5", "._", "1:20", "1,000", "0x_1F",
+		"0b101", ".Inf", "2001-12-14", "2001-1-2 3:04:05", ":sym", "<<", "=", "1.2.3", "v1"]`
+	testCases := []struct {
+		name    string
+		profile json2yaml.Profile
+		want    []string
+	}{
+		{
+			name:    "default",
+			profile: json2yaml.DefaultProfile,
+			want: []string{
+				`"yes"`, `"y"`, `"On"`, `"tRUE"`, `"NULL"`, `"nUll"`, `"0o17"`, `"017"`, `"08"`,
+				`"1_000"`, `+_1`, `"1e3"`, `"1.0e+3"`, `".5"`, `"._"`, `"1:20"`, `1,000`, `"0x_1F"`,
+				`"0b101"`, `".Inf"`, `"2001-12-14"`, `"2001-1-2 3:04:05"`, `:sym`, `"<<"`, `"="`, `1.2.3`, `v1`,
+			},
+		},
+		{
+			name:    "core schema",
+			profile: json2yaml.CoreSchemaProfile,
+			want: []string{
+				`yes`, `y`, `On`, `tRUE`, `"NULL"`, `nUll`, `"0o17"`, `"017"`, `"08"`,
+				`1_000`, `+_1`, `"1e3"`, `"1.0e+3"`, `".5"`, `._`, `1:20`, `1,000`, `0x_1F`,
+				`0b101`, `".Inf"`, `2001-12-14`, `2001-1-2 3:04:05`, `:sym`, `<<`, `=`, `1.2.3`, `v1`,
+			},
+		},
+		{
+			name:    "pyyaml",
+			profile: json2yaml.PyYAMLProfile,
+			want: []string{
+				`"yes"`, `y`, `"On"`, `tRUE`, `"NULL"`, `nUll`, `0o17`, `"017"`, `08`,
+				`"1_000"`, `+_1`, `1e3`, `"1.0e+3"`, `".5"`, `"._"`, `"1:20"`, `1,000`, `"0x_1F"`,
+				`"0b101"`, `".Inf"`, `"2001-12-14"`, `"2001-1-2 3:04:05"`, `:sym`, `"<<"`, `"="`, `1.2.3`, `v1`,
+			},
+		},
+		{
+			name:    "go-yaml v2",
+			profile: json2yaml.GoYAMLV2Profile,
+			want: []string{
+				`"yes"`, `"y"`, `"On"`, `tRUE`, `"NULL"`, `nUll`, `"0o17"`, `"017"`, `"08"`,
+				`"1_000"`, `"+_1"`, `"1e3"`, `"1.0e+3"`, `".5"`, `._`, `1:20`, `1,000`, `"0x_1F"`,
+				`"0b101"`, `".Inf"`, `"2001-12-14"`, `"2001-1-2 3:04:05"`, `:sym`, `"<<"`, `=`, `1.2.3`, `v1`,
+			},
+		},
+		{
+			name:    "go-yaml v3",
+			profile: json2yaml.GoYAMLV3Profile,
+			want: []string{
+				`yes`, `y`, `On`, `tRUE`, `"NULL"`, `nUll`, `"0o17"`, `"017"`, `"08"`,
+				`"1_000"`, `"+_1"`, `"1e3"`, `"1.0e+3"`, `".5"`, `._`, `1:20`, `1,000`, `"0x_1F"`,
+				`"0b101"`, `".Inf"`, `"2001-12-14"`, `"2001-1-2 3:04:05"`, `:sym`, `"<<"`, `=`, `1.2.3`, `v1`,
+			},
+		},
+		{
+			name:    "snakeyaml",
+			profile: json2yaml.SnakeYAMLProfile,
+			want: []string{
+				`"yes"`, `y`, `"On"`, `tRUE`, `"NULL"`, `nUll`, `0o17`, `"017"`, `"08"`,
+				`"1_000"`, `"+_1"`, `"1e3"`, `"1.0e+3"`, `".5"`, `._`, `"1:20"`, `1,000`, `"0x_1F"`,
+				`"0b101"`, `".Inf"`, `"2001-12-14"`, `"2001-1-2 3:04:05"`, `:sym`, `"<<"`, `"="`, `1.2.3`, `v1`,
+			},
+		},
+		{
+			name:    "psych",
+			profile: json2yaml.PsychProfile,
+			want: []string{
+				`"yes"`, `y`, `"On"`, `"tRUE"`, `"NULL"`, `"nUll"`, `0o17`, `"017"`, `"08"`,
+				`"1_000"`, `+_1`, `"1e3"`, `"1.0e+3"`, `".5"`, `._`, `"1:20"`, `"1,000"`, `"0x_1F"`,
+				`"0b101"`, `".Inf"`, `"2001-12-14"`, `"2001-1-2 3:04:05"`, `":sym"`, `"<<"`, `=`, `1.2.3`, `v1`,
+			},
+		},
+		{
+			name:    "unknown",
+			profile: json2yaml.Profile(-1),
+			want: []string{
+				`"yes"`, `"y"`, `"On"`, `"tRUE"`, `"NULL"`, `"nUll"`, `"0o17"`, `"017"`, `"08"`,
+				`"1_000"`, `+_1`, `"1e3"`, `"1.0e+3"`, `".5"`, `"._"`, `"1:20"`, `1,000`, `"0x_1F"`,
+				`"0b101"`, `".Inf"`, `"2001-12-14"`, `"2001-1-2 3:04:05"`, `:sym`, `"<<"`, `"="`, `1.2.3`, `v1`,
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var sb strings.Builder
+			err := json2yaml.Convert(&sb, strings.NewReader(src), json2yaml.WithProfile(tc.profile))
+			if err != nil {
+				t.Fatalf("should not raise an error but got: %s", err)
+			}
+			want := "- " + strings.Join(tc.want, "\n- ") + "\n"
+			if got, want := diff(sb.String(), want); got != want {
+				t.Fatalf("should write\n  %q\nbut got\n  %q", want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package json2yaml
+
+import (
+	"bytes"
+	"io"
+)
+
+// NewReader returns a reader of YAML converted from JSON read from r. The
+// conversion proceeds on demand of Read, buffering about 4 KiB of output.
+func NewReader(r io.Reader, opts ...Option) io.Reader {
+	rd := &reader{}
+	rd.c = newConverter(&rd.out, opts)
+	rd.r = r
+	return rd
+}
+
+type reader struct {
+	c   *converter
+	r   io.Reader
+	dec TokenReader
+	out bytes.Buffer
+	err error
+}
+
+func (r *reader) Read(p []byte) (int, error) {
+	if r.out.Len() == 0 && r.err == nil {
+		r.fill()
+	}
+	if r.out.Len() > 0 {
+		return r.out.Read(p)
+	}
+	return 0, r.err
+}
+
+// fill converts the tokens until the converter flushes the output.
+func (r *reader) fill() {
+	if r.dec == nil {
+		r.dec = r.c.newDecoder(r.r)
+		if r.err = r.c.start(); r.err != nil {
+			return
+		}
+	}
+	for r.out.Len() == 0 {
+		if err := r.c.step(r.dec); err != nil {
+			if err == io.EOF {
+				err = nil
+			}
+			if err = r.c.finish(err); err == nil {
+				err = io.EOF
+			}
+			r.err = err
+			return
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package json2yaml_test
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/itchyny/json2yaml"
+)
+
+func TestNewReader(t *testing.T) {
+	for _, tc := range convertTestCases() {
+		t.Run(tc.name, func(t *testing.T) {
+			var sb strings.Builder
+			err := json2yaml.Convert(&sb, strings.NewReader(tc.src))
+			bs, rerr := io.ReadAll(json2yaml.NewReader(strings.NewReader(tc.src)))
+			if got, want := diff(string(bs), sb.String()); got != want {
+				t.Fatalf("should read\n  %q\nbut got\n  %q", want, got)
+			}
+			if err == nil {
+				if rerr != nil {
+					t.Fatalf("should not raise an error but got: %s", rerr)
+				}
+			} else {
+				if rerr == nil {
+					t.Fatalf("should raise an error %q but got no error", err)
+				}
+				if rerr.Error() != err.Error() {
+					t.Fatalf("should raise an error %q but got error %q", err, rerr)
+				}
+			}
+		})
+	}
+}
+
+type countReader struct {
+	r io.Reader
+	n int
+}
+
+func (r *countReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.n += n
+	return n, err
+}
+
+func TestNewReaderIncremental(t *testing.T) {
+	src := "[" + strings.Repeat(`"foo",`, 100000) + `"foo"]`
+	cr := &countReader{r: strings.NewReader(src)}
+	r := json2yaml.NewReader(cr)
+	p := make([]byte, 16)
+	n, err := r.Read(p)
+	if err != nil {
+		t.Fatalf("should not raise an error but got: %s", err)
+	}
+	if got, want := string(p[:n]), "- foo\n- foo\n- fo"; got != want {
+		t.Fatalf("should read\n  %q\nbut got\n  %q", want, got)
+	}
+	if cr.n >= len(src)/10 {
+		t.Fatalf("should read the input incrementally but read %d bytes", cr.n)
+	}
+	bs, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("should not raise an error but got: %s", err)
+	}
+	if got, want := len(bs)+n, 100001*len("- foo\n"); got != want {
+		t.Fatalf("should read %d bytes but got %d bytes", want, got)
+	}
+}
@@ -0,0 +1,443 @@
+package json2yaml
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Schema is a JSON Schema loaded from local files.
+type Schema struct {
+	root *schema
+}
+
+// LoadSchema loads a JSON Schema from the file. The references ($ref) are
+// resolved within the file, and across the local files relative to it.
+func LoadSchema(name string) (*Schema, error) {
+	name, err := filepath.Abs(name)
+	if err != nil {
+		return nil, err
+	}
+	l := &schemaLoader{docs: map[string]any{}, schemas: map[string]*schema{}}
+	root, err := l.compile(name, "")
+	if err != nil {
+		return nil, err
+	}
+	return &Schema{root}, nil
+}
+
+type schema struct {
+	location             string
+	boolean              *bool
+	ref                  *schema
+	description          string
+	contentEncoding      string
+	types                []string
+	enum                 []any
+	constant             any
+	hasConst             bool
+	pattern              *regexp.Regexp
+	minLength, maxLength int
+	minimum, maximum     json.Number
+	exclusiveMinimum     json.Number
+	exclusiveMaximum     json.Number
+	multipleOf           json.Number
+	properties           map[string]*schema
+	patternProperties    []*patternSchema
+	additionalProperties *schema
+	required             []string
+	minProperties        int
+	maxProperties        int
+	items                *schema
+	prefixItems          []*schema
+	minItems, maxItems   int
+	allOf, anyOf, oneOf  []*schema
+	not                  *schema
+}
+
+type patternSchema struct {
+	pattern *regexp.Regexp
+	schema  *schema
+}
+
+// subschemas returns the schema and the subschemas applied to the same value,
+// following the references and the combinations of the schemas.
+func (s *schema) subschemas(yield func(*schema) bool) {
+	s.subschemasInternal(yield, map[*schema]bool{}, true)
+}
+
+// conjuncts returns the schema and the subschemas all of which the value must
+// be valid against, following the references and allOf.
+func (s *schema) conjuncts(yield func(*schema) bool) {
+	s.subschemasInternal(yield, map[*schema]bool{}, false)
+}
+
+func (s *schema) subschemasInternal(
+	yield func(*schema) bool, visited map[*schema]bool, disjuncts bool,
+) bool {
+	if visited[s] {
+		return true
+	}
+	visited[s] = true
+	if !yield(s) {
+		return false
+	}
+	if s.ref != nil && !s.ref.subschemasInternal(yield, visited, disjuncts) {
+		return false
+	}
+	for i, ss := range [][]*schema{s.allOf, s.anyOf, s.oneOf} {
+		if i > 0 && !disjuncts {
+			break
+		}
+		for _, s := range ss {
+			if !s.subschemasInternal(yield, visited, disjuncts) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// property returns the schema of the property of the object.
+func (s *schema) property(key string) *schema {
+	if s == nil {
+		return nil
+	}
+	for s := range s.subschemas {
+		if t, ok := s.properties[key]; ok {
+			return t
+		}
+		for _, p := range s.patternProperties {
+			if p.pattern.MatchString(key) {
+				return p.schema
+			}
+		}
+		if s.additionalProperties != nil {
+			return s.additionalProperties
+		}
+	}
+	return nil
+}
+
+// item returns the schema of the element at the index of the array.
+func (s *schema) item(index int) *schema {
+	if s == nil {
+		return nil
+	}
+	for s := range s.subschemas {
+		if index < len(s.prefixItems) {
+			return s.prefixItems[index]
+		}
+		if s.items != nil {
+			return s.items
+		}
+	}
+	return nil
+}
+
+// lookup returns the first non-empty result of the function on the schema and
+// the subschemas applied to the same value.
+func (s *schema) lookup(f func(*schema) string) string {
+	if s != nil {
+		for s := range s.subschemas {
+			if v := f(s); v != "" {
+				return v
+			}
+		}
+	}
+	return ""
+}
+
+type schemaLoader struct {
+	docs    map[string]any
+	schemas map[string]*schema
+}
+
+func (l *schemaLoader) load(name string) (any, error) {
+	if doc, ok := l.docs[name]; ok {
+		return doc, nil
+	}
+	bs, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(bs))
+	dec.UseNumber()
+	var doc any
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	l.docs[name] = doc
+	return doc, nil
+}
+
+func (l *schemaLoader) compile(name, pointer string) (*schema, error) {
+	location := name + "#" + pointer
+	if s, ok := l.schemas[location]; ok {
+		return s, nil
+	}
+	doc, err := l.load(name)
+	if err != nil {
+		return nil, err
+	}
+	v, err := lookupPointer(doc, pointer)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", location, err)
+	}
+	s := &schema{location: location, maxLength: -1, maxProperties: -1, maxItems: -1}
+	l.schemas[location] = s
+	if err := l.compileValue(s, v, name, pointer); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (l *schemaLoader) compileValue(s *schema, v any, name, pointer string) (err error) {
+	if b, ok := v.(bool); ok {
+		s.boolean = &b
+		return nil
+	}
+	m, ok := v.(map[string]any)
+	if !ok {
+		return fmt.Errorf("%s: schema must be an object or a boolean", s.location)
+	}
+	compile := func(tokens ...string) (*schema, error) {
+		p := pointer
+		for _, token := range tokens {
+			p += "/" + strings.NewReplacer("~", "~0", "/", "~1").Replace(token)
+		}
+		return l.compile(name, p)
+	}
+	for key, v := range m {
+		switch key {
+		case "$ref":
+			ref, ok := v.(string)
+			if !ok {
+				return fmt.Errorf("%s: $ref must be a string", s.location)
+			}
+			if s.ref, err = l.compileRef(name, ref); err != nil {
+				return fmt.Errorf("%s: %w", s.location, err)
+			}
+		case "description":
+			s.description, _ = v.(string)
+		case "contentEncoding":
+			s.contentEncoding, _ = v.(string)
+		case "type":
+			switch v := v.(type) {
+			case string:
+				s.types = []string{v}
+			case []any:
+				for _, v := range v {
+					if t, ok := v.(string); ok {
+						s.types = append(s.types, t)
+					}
+				}
+			}
+		case "enum":
+			s.enum, _ = v.([]any)
+		case "const":
+			s.constant, s.hasConst = v, true
+		case "pattern":
+			if p, ok := v.(string); ok {
+				if s.pattern, err = regexp.Compile(p); err != nil {
+					return fmt.Errorf("%s: %w", s.location, err)
+				}
+			}
+		case "minLength":
+			s.minLength = compileCount(v, s.minLength)
+		case "maxLength":
+			s.maxLength = compileCount(v, s.maxLength)
+		case "minimum":
+			s.minimum, _ = v.(json.Number)
+		case "maximum":
+			s.maximum, _ = v.(json.Number)
+		case "exclusiveMinimum":
+			s.exclusiveMinimum, _ = v.(json.Number)
+		case "exclusiveMaximum":
+			s.exclusiveMaximum, _ = v.(json.Number)
+		case "multipleOf":
+			s.multipleOf, _ = v.(json.Number)
+		case "required":
+			vs, _ := v.([]any)
+			for _, v := range vs {
+				if k, ok := v.(string); ok {
+					s.required = append(s.required, k)
+				}
+			}
+		case "minProperties":
+			s.minProperties = compileCount(v, s.minProperties)
+		case "maxProperties":
+			s.maxProperties = compileCount(v, s.maxProperties)
+		case "minItems":
+			s.minItems = compileCount(v, s.minItems)
+		case "maxItems":
+			s.maxItems = compileCount(v, s.maxItems)
+		case "properties":
+			m, _ := v.(map[string]any)
+			s.properties = make(map[string]*schema, len(m))
+			for k := range m {
+				if s.properties[k], err = compile(key, k); err != nil {
+					return err
+				}
+			}
+		case "patternProperties":
+			m, _ := v.(map[string]any)
+			for k := range m {
+				p := &patternSchema{}
+				if p.pattern, err = regexp.Compile(k); err != nil {
+					return fmt.Errorf("%s: %w", s.location, err)
+				}
+				if p.schema, err = compile(key, k); err != nil {
+					return err
+				}
+				s.patternProperties = append(s.patternProperties, p)
+			}
+		case "additionalProperties":
+			if s.additionalProperties, err = compile(key); err != nil {
+				return err
+			}
+		case "items":
+			if _, ok := v.([]any); ok {
+				// array form of items in draft 2019-09 and before
+				if s.prefixItems, err = l.compileArray(v, compile, key); err != nil {
+					return err
+				}
+				if _, ok := m["additionalItems"]; ok {
+					if s.items, err = compile("additionalItems"); err != nil {
+						return err
+					}
+				}
+			} else if s.items, err = compile(key); err != nil {
+				return err
+			}
+		case "prefixItems":
+			if s.prefixItems, err = l.compileArray(v, compile, key); err != nil {
+				return err
+			}
+		case "allOf":
+			if s.allOf, err = l.compileArray(v, compile, key); err != nil {
+				return err
+			}
+		case "anyOf":
+			if s.anyOf, err = l.compileArray(v, compile, key); err != nil {
+				return err
+			}
+		case "oneOf":
+			if s.oneOf, err = l.compileArray(v, compile, key); err != nil {
+				return err
+			}
+		case "not":
+			if s.not, err = compile(key); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (l *schemaLoader) compileArray(
+	v any, compile func(...string) (*schema, error), key string,
+) ([]*schema, error) {
+	vs, _ := v.([]any)
+	ss := make([]*schema, len(vs))
+	for i := range vs {
+		var err error
+		if ss[i], err = compile(key, strconv.Itoa(i)); err != nil {
+			return nil, err
+		}
+	}
+	return ss, nil
+}
+
+func compileCount(v any, def int) int {
+	if n, ok := v.(json.Number); ok {
+		if i, err := n.Int64(); err == nil && i >= 0 && i <= math.MaxInt32 {
+			return int(i)
+		}
+	}
+	return def
+}
+
+func (l *schemaLoader) compileRef(name, ref string) (*schema, error) {
+	u, err := url.Parse(ref)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "" || u.Host != "" {
+		return nil, fmt.Errorf("cannot resolve non-local reference: %s", ref)
+	}
+	if u.Path != "" {
+		name = filepath.Join(filepath.Dir(name), filepath.FromSlash(u.Path))
+	}
+	if u.Fragment != "" && !strings.HasPrefix(u.Fragment, "/") {
+		return nil, fmt.Errorf("cannot resolve reference to anchor: %s", ref)
+	}
+	return l.compile(name, u.Fragment)
+}
+
+func lookupPointer(v any, pointer string) (any, error) {
+	if pointer == "" {
+		return v, nil
+	}
+	for _, token := range strings.Split(pointer[1:], "/") {
+		token = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+		switch w := v.(type) {
+		case map[string]any:
+			var ok bool
+			if v, ok = w[token]; ok {
+				continue
+			}
+		case []any:
+			if i, err := strconv.Atoi(token); err == nil && 0 <= i && i < len(w) {
+				v = w[i]
+				continue
+			}
+		}
+		return nil, fmt.Errorf("cannot resolve JSON pointer: %s", pointer)
+	}
+	return v, nil
+}
+
+// schemaTracker tracks the schema of the value on converting the tokens.
+type schemaTracker struct {
+	root   *schema
+	frames []schemaFrame
+	next   *schema // schema of the next value
+}
+
+type schemaFrame struct {
+	schema *schema
+	index  int
+}
+
+// track tracks the schema of the token in the parent, which is the top of the
+// stack of the converter.
+func (t *schemaTracker) track(parent byte, token json.Token) {
+	switch parent {
+	case '.':
+		t.next = t.root
+	case '{':
+		if key, ok := token.(string); ok {
+			t.next = t.frames[len(t.frames)-1].schema.property(key)
+		}
+	case '[':
+		if token != json.Delim(']') {
+			f := &t.frames[len(t.frames)-1]
+			t.next = f.schema.item(f.index)
+			f.index++
+		}
+	}
+	switch token {
+	case json.Delim('{'), json.Delim('['):
+		t.frames = append(t.frames, schemaFrame{schema: t.next})
+	case json.Delim('}'), json.Delim(']'):
+		t.frames = t.frames[:len(t.frames)-1]
+	}
+}
@@ -0,0 +1,308 @@
+package json2yaml_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/itchyny/json2yaml"
+)
+
+func writeSchemaFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		name = filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(name, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestConvertSchemaComments(t *testing.T) {
+	dir := writeSchemaFiles(t, map[string]string{
+		"schema.json": `{
+			"$schema": "https://json-schema.org/draft/2020-12/schema",
+			"description": "The root object",
+			"type": "object",
+			"properties": {
+				"name": {"description": "The name of the item", "type": "string"},
+				"tags": {
+					"description": "The tags\nof the item  \n\n",
+					"type": "array",
+					"items": {"$ref": "#/$defs/tag"}
+				},
+				"point": {"prefixItems": [{"description": "x"}], "items": {"$ref": "#/$defs/tag"}},
+				"owner": {"$ref": "defs/owner.json"},
+				"data": {"description": "Binary data", "contentEncoding": "base64"},
+				"nested": {"$ref": "#/$defs/nested"},
+				"a/b~c": {"description": "Escaped key"}
+			},
+			"patternProperties": {"^x-": {"description": "Extension"}},
+			"additionalProperties": {"description": "Other property"},
+			"$defs": {
+				"tag": {
+					"type": "object",
+					"properties": {"key": {"description": "The key of the tag"}, "value": {}}
+				},
+				"nested": {
+					"description": "Nested object",
+					"allOf": [{"$ref": "#/$defs/nested"}],
+					"properties": {"nested": {"$ref": "#/$defs/nested"}, "legacy": {"items": [{}, {"$ref": "#/$defs/tag"}]}}
+				}
+			}
+		}`,
+		"defs/owner.json": `{
+			"description": "The owner",
+			"allOf": [{"$ref": "person.json#/definitions/person"}],
+			"properties": {"id": {"description": "The identifier"}}
+		}`,
+		"defs/person.json": `{
+			"definitions": {
+				"person": {"properties": {"name": {"description": "The name of the person"}}}
+			}
+		}`,
+	})
+	s, err := json2yaml.LoadSchema(filepath.Join(dir, "schema.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := `{"name": "foo", "tags": [{"key": "x", "value": 1}, {"value": 2, "key": "y"}],
+		"point": [{"key": 0}, {"key": 1}], "owner": {"id": 1, "name": "bar", "age": 20},
+		"data": "aGVsbG8=", "nested": {"nested": {"nested": {"x": 1}, "legacy": [{"key": 1}, {"key": 2}]}},
+		"a/b~c": null, "x-foo": "a\nb", "y": {"key": 1}}
+		[{"name": [1]}] {"name": "baz"}`
+	want := `# The name of the item
+name: foo
+# The tags
+# of the item
+#
+tags:
+  - # The key of the tag
+    key: x
+    value: 1
+  - value: 2
+    # The key of the tag
+    key: "y"
+point:
+  - key: 0
+  - # The key of the tag
+    key: 1
+# The owner
+owner:
+  # The identifier
+  id: 1
+  # The name of the person
+  name: bar
+  age: 20
+# Binary data
+data: !!binary |
+  aGVsbG8=
+# Nested object
+nested:
+  # Nested object
+  nested:
+    # Nested object
+    nested:
+      x: 1
+    legacy:
+      - key: 1
+      - # The key of the tag
+        key: 2
+# Escaped key
+a/b~c: null
+# Extension
+x-foo: |-
+  a
+  b
+# Other property
+"y":
+  key: 1
+---
+- name:
+    - 1
+---
+# The name of the item
+name: baz
+`
+	var sb strings.Builder
+	if err := json2yaml.Convert(&sb, strings.NewReader(src), json2yaml.WithSchemaComments(s),
+		json2yaml.WithBinarySchema(s)); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := diff(sb.String(), want); got != want {
+		t.Fatalf("should write\n  %q\nbut got\n  %q", want, got)
+	}
+}
+
+func TestConvertBinarySchema(t *testing.T) {
+	dir := writeSchemaFiles(t, map[string]string{
+		"schema.json": `{
+			"$schema": "https://json-schema.org/draft/2020-12/schema",
+			"type": "object",
+			"properties": {
+				"data": {"contentEncoding": "base64"},
+				"text": {"type": "string"},
+				"list": {"items": {"contentEncoding": "base64"}},
+				"point": {"prefixItems": [{}], "items": {"$ref": "#/$defs/binary"}},
+				"owner": {"$ref": "defs/owner.json"},
+				"nested": {"$ref": "#/$defs/nested"},
+				"a/b~c": {"contentEncoding": "base64"},
+				"any": true
+			},
+			"patternProperties": {"^x-": {"contentEncoding": "base64"}},
+			"additionalProperties": {"properties": {"key": {"$ref": "#/$defs/binary"}}},
+			"$defs": {
+				"binary": {"contentEncoding": "base64"},
+				"nested": {
+					"allOf": [{"$ref": "#/$defs/nested"}],
+					"properties": {"nested": {"$ref": "#/$defs/nested"}, "legacy": {"items": [{}, {"$ref": "#/$defs/binary"}]}}
+				}
+			}
+		}`,
+		"defs/owner.json": `{
+			"anyOf": [{"$ref": "person.json#/definitions/person"}],
+			"properties": {"id": {}}
+		}`,
+		"defs/person.json": `{
+			"definitions": {
+				"person": {"properties": {"icon": {"contentEncoding": "base64"}}}
+			}
+		}`,
+		"comments.json": `{"properties": {"other": {"description": "Other data"}}}`,
+	})
+	s, err := json2yaml.LoadSchema(filepath.Join(dir, "schema.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cs, err := json2yaml.LoadSchema(filepath.Join(dir, "comments.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := `{"data": "aGVsbG8=", "text": "aGVsbG8=", "list": ["d29ybGQ=", "foo", "@@@@"],
+		"point": ["aGVsbG8=", "aGVsbG8="], "owner": {"id": "aGVsbG8=", "icon": "aGVsbG8="},
+		"nested": {"nested": {"nested": {"x": 1}, "legacy": ["aGVsbG8=", "aGVsbG8="]}},
+		"a/b~c": "aGVsbG8=", "x-foo": "aGVsbG8=", "y": {"key": "aGVsbG8=", "z": {"a": ["aGVsbG8="]}}, "any": "aGVsbG8=", "other": "aGVsbG8="}
+		["aGVsbG8="] {"data": "d29ybGQ="}`
+	want := `data: !!binary |
+  aGVsbG8=
+text: aGVsbG8=
+list:
+  - !!binary |
+    d29ybGQ=
+  - foo
+  - "@@@@"
+point:
+  - aGVsbG8=
+  - !!binary |
+    aGVsbG8=
+owner:
+  id: aGVsbG8=
+  icon: !!binary |
+    aGVsbG8=
+nested:
+  nested:
+    nested:
+      x: 1
+    legacy:
+      - aGVsbG8=
+      - !!binary |
+        aGVsbG8=
+a/b~c: !!binary |
+  aGVsbG8=
+x-foo: !!binary |
+  aGVsbG8=
+"y":
+  key: !!binary |
+    aGVsbG8=
+  z:
+    a:
+      - aGVsbG8=
+any: aGVsbG8=
+# Other data
+other: aGVsbG8=
+---
+- aGVsbG8=
+---
+data: !!binary |
+  d29ybGQ=
+`
+	var sb strings.Builder
+	if err := json2yaml.Convert(&sb, strings.NewReader(src), json2yaml.WithBinarySchema(s),
+		json2yaml.WithSchemaComments(cs)); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := diff(sb.String(), want); got != want {
+		t.Fatalf("should write\n  %q\nbut got\n  %q", want, got)
+	}
+}
+
+func TestLoadSchemaError(t *testing.T) {
+	dir := writeSchemaFiles(t, map[string]string{
+		"invalid.json":  `{`,
+		"string.json":   `{"properties": {"x": "string"}}`,
+		"ref.json":      `{"$ref": 1}`,
+		"pointer.json":  `{"items": {"$ref": "#/$defs/x"}}`,
+		"remote.json":   `{"$ref": "https://example.com/schema.json"}`,
+		"anchor.json":   `{"$ref": "#foo"}`,
+		"missing.json":  `{"$ref": "other.json"}`,
+		"pattern.json":  `{"patternProperties": {"(": {}}}`,
+		"url.json":      `{"$ref": "%"}`,
+		"index.json":    `{"prefixItems": [{"$ref": "#/prefixItems/1"}]}`,
+		"nested.json":   `{"anyOf": [{"oneOf": [{"allOf": [{"additionalProperties": {"$ref": "#/x"}}]}]}]}`,
+		"addition.json": `{"items": [], "additionalItems": {"$ref": "#/x"}}`,
+		"patterns.json": `{"patternProperties": {"^x-": {"$ref": "#/x"}}}`,
+		"items.json":    `{"items": [{"$ref": "#/x"}]}`,
+		"regexp.json":   `{"pattern": "("}`,
+		"not.json":      `{"not": {"$ref": "#/x"}}`,
+	})
+	testCases := []struct {
+		name string
+		err  string
+	}{
+		{"notfound.json", "no such file or directory"},
+		{"invalid.json", "invalid.json: unexpected EOF"},
+		{"string.json", "string.json#/properties/x: schema must be an object or a boolean"},
+		{"ref.json", "ref.json#: $ref must be a string"},
+		{"pointer.json", "pointer.json#/$defs/x: cannot resolve JSON pointer: /$defs/x"},
+		{"remote.json", "remote.json#: cannot resolve non-local reference: https://example.com/schema.json"},
+		{"anchor.json", "anchor.json#: cannot resolve reference to anchor: #foo"},
+		{"missing.json", "missing.json#: open "},
+		{"pattern.json", "pattern.json#: error parsing regexp"},
+		{"url.json", "url.json#: parse \"%\""},
+		{"index.json", "index.json#/prefixItems/1: cannot resolve JSON pointer: /prefixItems/1"},
+		{"nested.json", "nested.json#/x: cannot resolve JSON pointer: /x"},
+		{"addition.json", "addition.json#/x: cannot resolve JSON pointer: /x"},
+		{"patterns.json", "patterns.json#/x: cannot resolve JSON pointer: /x"},
+		{"items.json", "items.json#/x: cannot resolve JSON pointer: /x"},
+		{"regexp.json", "regexp.json#: error parsing regexp"},
+		{"not.json", "not.json#/x: cannot resolve JSON pointer: /x"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := json2yaml.LoadSchema(filepath.Join(dir, tc.name))
+			if err == nil {
+				t.Fatalf("should raise an error %q but got no error", tc.err)
+			}
+			if !strings.Contains(err.Error(), tc.err) {
+				t.Fatalf("should raise an error %q but got error %q", tc.err, err)
+			}
+		})
+	}
+}
+
+func TestLoadSchemaWorkingDirectory(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	if err := os.Remove(dir); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := json2yaml.LoadSchema("schema.json"); err == nil {
+		t.Fatalf("should raise an error but got no error")
+	}
+}
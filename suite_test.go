@@ -0,0 +1,198 @@
+package json2yaml_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/itchyny/json2yaml"
+	"github.com/itchyny/json2yaml/yaml2json"
+)
+
+// The cases of https://github.com/yaml/yaml-test-suite, copied from the
+// testdata of github.com/goccy/go-yaml, which can be updated by
+// make update-test-suite (see testdata/yaml-test-suite/README.md).
+const yamlTestSuite = "testdata/yaml-test-suite"
+
+// The cases of the test suite which yaml2json does not parse as expected.
+var yaml2jsonSuiteFailures = map[string]string{
+	"allowed-characters-in-alias":                             "anchor names with colons",
+	"anchors-with-colon-in-name":                              "anchor names with colons",
+	"construct-binary":                                        "line breaks are removed from !!binary scalars",
+	"tabs-that-look-like-indentation/00":                      "tabs after the indentation",
+	"trailing-line-of-spaces/01":                              "spaces-only lines in block scalars",
+	"trailing-whitespace-in-streams/01":                       "spaces-only lines in block scalars",
+	"trailing-whitespace-in-streams/02":                       "spaces-only lines in block scalars",
+	"anchor-before-sequence-entry-on-same-line":               "invalid input is accepted",
+	"block-scalar-with-more-spaces-than-first-content-line":   "invalid input is accepted",
+	"block-scalar-with-wrong-indented-line-after-spaces-only": "invalid input is accepted",
+	"directive-variants/00":                                   "invalid input is accepted",
+	"duplicate-yaml-directive":                                "invalid input is accepted",
+	"flow-collections-over-many-lines/00":                     "invalid input is accepted",
+	"implicit-key-followed-by-newline":                        "invalid input is accepted",
+	"implicit-key-followed-by-newline-and-adjacent-value":     "invalid input is accepted",
+	"invalid-comment-after-comma":                             "invalid input is accepted",
+	"invalid-document-end-marker-in-single-quoted-string":     "invalid input is accepted",
+	"invalid-document-start-marker-in-doublequoted-tring":     "invalid input is accepted",
+	"literal-block-scalar-with-more-spaces-in-first-line":     "invalid input is accepted",
+	"scalar-doc-with-in-content/01":                           "invalid input is accepted",
+	"scalar-value-with-two-anchors":                           "invalid input is accepted",
+	"tabs-in-various-contexts/000":                            "invalid input is accepted",
+	"tabs-in-various-contexts/003":                            "invalid input is accepted",
+	"tabs-in-various-contexts/004":                            "invalid input is accepted",
+	"tabs-in-various-contexts/005":                            "invalid input is accepted",
+	"tabs-that-look-like-indentation/01":                      "invalid input is accepted",
+	"wrong-indented-flow-sequence":                            "invalid input is accepted",
+	"wrong-indented-multiline-quoted-scalar":                  "invalid input is accepted",
+}
+
+func TestYAML2JSONYAMLTestSuite(t *testing.T) {
+	var count int
+	if err := filepath.WalkDir(yamlTestSuite, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || d.Name() != "in.yaml" {
+			return err
+		}
+		dir := filepath.Dir(path)
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		var want []any
+		_, err = os.Stat(filepath.Join(dir, "error"))
+		if invalid := err == nil; !invalid {
+			bs, err := os.ReadFile(filepath.Join(dir, "in.json"))
+			if err != nil {
+				if errors.Is(err, fs.ErrNotExist) {
+					return nil // not representable in JSON
+				}
+				return err
+			}
+			if want, err = decodeValues(bytes.NewReader(bs)); err != nil {
+				return err
+			}
+		}
+		count++
+		name, _ := filepath.Rel(yamlTestSuite, dir)
+		name = filepath.ToSlash(name)
+		t.Run(name, func(t *testing.T) {
+			reason, known := yaml2jsonSuiteFailures[name]
+			var buf bytes.Buffer
+			err := yaml2json.Convert(&buf, bytes.NewReader(src))
+			var msg string
+			if want == nil {
+				if err == nil {
+					msg = "should raise an error but got no error"
+				}
+			} else if err != nil {
+				msg = "should not raise an error but got: " + err.Error()
+			} else if got, err := decodeValues(&buf); err != nil {
+				msg = "should write a valid JSON but got: " + err.Error()
+			} else if !reflect.DeepEqual(got, want) {
+				msg = "should write\n  " + fmtValues(want) + "\nbut got\n  " + fmtValues(got)
+			}
+			if known {
+				if msg == "" {
+					t.Fatalf("should be removed from the known failures: %s", reason)
+				}
+				t.Skipf("known failure: %s", reason)
+			}
+			if msg != "" {
+				t.Fatalf("%s\n%s", msg, src)
+			}
+		})
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if count == 0 {
+		t.Fatalf("%s has no test cases", yamlTestSuite)
+	}
+}
+
+func TestConvertYAMLTestSuite(t *testing.T) {
+	optionSets := []struct {
+		name string
+		opts []json2yaml.Option
+	}{
+		{name: "default"},
+		{name: "ascii", opts: []json2yaml.Option{json2yaml.WithASCII()}},
+		{name: "string tags", opts: []json2yaml.Option{json2yaml.WithStringTags()}},
+		{name: "document markers", opts: []json2yaml.Option{
+			json2yaml.WithDocumentStart(), json2yaml.WithDocumentEnd(),
+		}},
+	}
+	var count int
+	if err := filepath.WalkDir(yamlTestSuite, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || d.Name() != "in.json" {
+			return err
+		}
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		want, err := decodeTokens(bytes.NewReader(src))
+		if err != nil {
+			return err
+		}
+		count++
+		name, _ := filepath.Rel(yamlTestSuite, filepath.Dir(path))
+		t.Run(filepath.ToSlash(name), func(t *testing.T) {
+			for _, set := range optionSets {
+				var sb strings.Builder
+				if err := json2yaml.Convert(&sb, bytes.NewReader(src), set.opts...); err != nil {
+					t.Fatalf("%s: should not raise an error but got: %s", set.name, err)
+				}
+				// yaml2json is checked against the test suite above
+				var buf bytes.Buffer
+				if err := yaml2json.Convert(&buf, strings.NewReader(sb.String())); err != nil {
+					t.Fatalf("%s: should write a valid YAML but got: %s\n%s",
+						set.name, err, sb.String())
+				}
+				got, err := decodeTokens(&buf)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !slices.Equal(got, want) {
+					t.Fatalf("%s: should parse back to %v but got %v\n%s",
+						set.name, want, got, sb.String())
+				}
+			}
+		})
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if count == 0 {
+		t.Fatalf("%s has no test cases", yamlTestSuite)
+	}
+}
+
+// decodeValues decodes the JSON values, where the order of the object keys
+// does not matter.
+func decodeValues(r io.Reader) ([]any, error) {
+	dec := json.NewDecoder(r)
+	values := []any{}
+	for {
+		var v any
+		if err := dec.Decode(&v); err != nil {
+			if err == io.EOF {
+				return values, nil
+			}
+			return nil, err
+		}
+		values = append(values, v)
+	}
+}
+
+func fmtValues(values []any) string {
+	bs, _ := json.Marshal(values)
+	return string(bs)
+}
@@ -0,0 +1,109 @@
+package json2yaml
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+)
+
+// TokenReader is the interface to read JSON tokens, implemented by
+// [*json.Decoder]. Token returns the tokens of [json.Delim], bool, float64,
+// [json.Number], string and nil, and returns [io.EOF] at the end of input.
+// More reports whether there is another element in the current array or
+// object.
+type TokenReader interface {
+	Token() (json.Token, error)
+	More() bool
+}
+
+// ConvertTokens reads JSON tokens from r and writes YAML to w. The token
+// sequence is checked to be well-formed. Note that [WithInvalidUTF8] has no
+// effect, since the strings are already decoded.
+func ConvertTokens(w io.Writer, r TokenReader, opts ...Option) error {
+	return newConverter(w, opts).convertTokens(&tokenChecker{r: r})
+}
+
+// tokenChecker checks the tokens from the reader, since the converter assumes
+// the well-formed token sequence like the one from [*json.Decoder].
+type tokenChecker struct {
+	r     TokenReader
+	stack []byte // '{' before a key, ':' before a value in object, '['
+	more  int    // result of the last More, 1 for true and -1 for false
+}
+
+var numberPattern = regexp.MustCompile(`^-?(?:0|[1-9][0-9]*)(?:\.[0-9]+)?(?:[eE][-+]?[0-9]+)?$`)
+
+func (r *tokenChecker) More() bool {
+	more := r.r.More()
+	if r.more = -1; more {
+		r.more = 1
+	}
+	return more
+}
+
+func (r *tokenChecker) Token() (json.Token, error) {
+	token, err := r.r.Token()
+	if err != nil {
+		return nil, err
+	}
+	return r.check(token)
+}
+
+// check checks the token, and converts float64 to [json.Number].
+func (r *tokenChecker) check(token json.Token) (json.Token, error) {
+	more := r.more
+	r.more = 0
+	switch v := token.(type) {
+	case json.Delim:
+		switch v {
+		case '{', '[':
+		case '}', ']':
+			if n := len(r.stack); n == 0 || r.stack[n-1] != byte(v)-2 || more > 0 {
+				return nil, fmt.Errorf("unexpected %s", formatToken(token))
+			}
+			r.stack = r.stack[:len(r.stack)-1]
+			r.complete()
+			return token, nil
+		default:
+			return nil, fmt.Errorf("invalid delimiter: %s", v)
+		}
+	case float64:
+		if math.IsInf(v, 0) || math.IsNaN(v) {
+			return nil, fmt.Errorf("invalid number: %v", v)
+		}
+		token = json.Number(formatFloat(v, 64))
+	case json.Number:
+		if !numberPattern.MatchString(string(v)) {
+			return nil, fmt.Errorf("invalid number: %s", v)
+		}
+	case bool, string, nil:
+	default:
+		return nil, fmt.Errorf("invalid token type: %T", token)
+	}
+	if more < 0 {
+		return nil, fmt.Errorf("unexpected %s", formatToken(token))
+	}
+	if n := len(r.stack); n > 0 && r.stack[n-1] == '{' {
+		if _, ok := token.(string); !ok {
+			return nil, fmt.Errorf("unexpected %s for object key", formatToken(token))
+		}
+		r.stack[n-1] = ':'
+		return token, nil
+	}
+	switch token {
+	case json.Delim('{'), json.Delim('['):
+		r.stack = append(r.stack, byte(token.(json.Delim)))
+	default:
+		r.complete()
+	}
+	return token, nil
+}
+
+// complete updates the state after a value is completed.
+func (r *tokenChecker) complete() {
+	if n := len(r.stack); n > 0 && r.stack[n-1] == ':' {
+		r.stack[n-1] = '{'
+	}
+}
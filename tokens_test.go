@@ -0,0 +1,137 @@
+package json2yaml_test
+
+import (
+	"encoding/json"
+	"io"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/itchyny/json2yaml"
+)
+
+type tokenSliceReader struct {
+	tokens []json.Token
+	noMore bool
+}
+
+func (r *tokenSliceReader) Token() (json.Token, error) {
+	if len(r.tokens) == 0 {
+		return nil, io.EOF
+	}
+	token := r.tokens[0]
+	r.tokens = r.tokens[1:]
+	return token, nil
+}
+
+func (r *tokenSliceReader) More() bool {
+	return !r.noMore && len(r.tokens) > 0 &&
+		r.tokens[0] != json.Delim('}') && r.tokens[0] != json.Delim(']')
+}
+
+func TestConvertTokens(t *testing.T) {
+	testCases := []struct {
+		name   string
+		tokens []json.Token
+		noMore bool
+		want   string
+		err    string
+	}{
+		{
+			name: "values",
+			tokens: []json.Token{
+				json.Delim('{'), "a", json.Delim('['), 1.0, 2.5, 1e21, json.Number("1.50"), json.Delim(']'),
+				"b", json.Delim('{'), json.Delim('}'), "c", true, "d", nil, json.Delim('}'),
+				"e", json.Delim('['), json.Delim(']'),
+			},
+			want: "a:\n  - 1\n  - 2.5\n  - 1e+21\n  - 1.50\nb: {}\nc: true\nd: null\n---\ne\n---\n[]\n",
+		},
+		{
+			name:   "unexpected end of array",
+			tokens: []json.Token{json.Delim('{'), json.Delim(']')},
+			want:   "",
+			err:    "unexpected end of array",
+		},
+		{
+			name:   "unexpected end of object",
+			tokens: []json.Token{json.Delim('{'), "a", json.Delim('}')},
+			want:   "a:",
+			err:    "unexpected end of object",
+		},
+		{
+			name:   "unexpected end of array at top level",
+			tokens: []json.Token{json.Delim(']')},
+			err:    "unexpected end of array",
+		},
+		{
+			name:   "unexpected object key",
+			tokens: []json.Token{json.Delim('{'), json.Number("1")},
+			err:    "unexpected number 1 for object key",
+		},
+		{
+			name:   "unexpected end of input",
+			tokens: []json.Token{json.Delim('['), "a"},
+			want:   "- a\n",
+			err:    "unexpected EOF",
+		},
+		{
+			name:   "invalid delimiter",
+			tokens: []json.Token{json.Delim(',')},
+			err:    "invalid delimiter: ,",
+		},
+		{
+			name:   "invalid token type",
+			tokens: []json.Token{1},
+			err:    "invalid token type: int",
+		},
+		{
+			name:   "invalid number",
+			tokens: []json.Token{json.Number("0x10")},
+			err:    "invalid number: 0x10",
+		},
+		{
+			name:   "infinity",
+			tokens: []json.Token{math.Inf(1)},
+			err:    "invalid number: +Inf",
+		},
+		{
+			name:   "inconsistent more",
+			tokens: []json.Token{json.Delim('['), "a", json.Delim(']')},
+			noMore: true,
+			err:    `unexpected "a"`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var sb strings.Builder
+			err := json2yaml.ConvertTokens(&sb, &tokenSliceReader{tc.tokens, tc.noMore})
+			if tc.err == "" {
+				if err != nil {
+					t.Fatalf("should not raise an error but got: %s", err)
+				}
+				if got, want := diff(sb.String(), tc.want); got != want {
+					t.Fatalf("should write\n  %q\nbut got\n  %q", want, got)
+				}
+			} else {
+				if err == nil {
+					t.Fatalf("should raise an error %q but got no error", tc.err)
+				}
+				if err.Error() != tc.err {
+					t.Fatalf("should raise an error %q but got error %q", tc.err, err)
+				}
+			}
+		})
+	}
+}
+
+func TestConvertTokensDecoder(t *testing.T) {
+	var sb strings.Builder
+	dec := json.NewDecoder(strings.NewReader(`{"a": [1, 2.50, 1e21, "b"]} []`))
+	if err := json2yaml.ConvertTokens(&sb, dec); err != nil {
+		t.Fatalf("should not raise an error but got: %s", err)
+	}
+	want := "a:\n  - 1\n  - 2.5\n  - 1e+21\n  - b\n---\n[]\n"
+	if got, want := diff(sb.String(), want); got != want {
+		t.Fatalf("should write\n  %q\nbut got\n  %q", want, got)
+	}
+}
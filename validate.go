@@ -0,0 +1,486 @@
+package json2yaml
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// ValidationError is a violation of the JSON Schema reported by
+// [WithSchemaValidation]. The Path is a JSONPath of the violating value, like
+// $.foo[0].bar.
+type ValidationError struct {
+	Path    string
+	Message string
+}
+
+func (err *ValidationError) Error() string {
+	return err.Path + ": " + err.Message
+}
+
+// Errors returned on exceeding the limits of the schema validation, which stop
+// the conversion immediately.
+var (
+	ErrValidationErrorLimit   = errors.New("too many validation errors")
+	ErrValidationCaptureLimit = errors.New("value is too large to validate")
+)
+
+const (
+	maxValidationErrors = 100
+	maxCaptureSize      = 1024 * 1024 // in bytes of the strings and numbers
+)
+
+// validator validates the JSON token stream against the schema. The keywords
+// applied to the individual values are checked in streaming fashion, while the
+// tokens of the values are captured to check anyOf, oneOf, not, enum and const.
+type validator struct {
+	root     *schema
+	frames   []validationFrame
+	captures []*validationCapture
+	errs     []error
+	err      error // on exceeding the limits
+}
+
+type validationFrame struct {
+	schemas   []*schema
+	object    bool
+	expectKey bool
+	key       string
+	index     int // number of the properties or the index of the element
+	keys      map[string]bool
+	next      []*schema
+}
+
+type validationCapture struct {
+	schema *schema
+	depth  int
+	tokens []json.Token
+	size   int
+}
+
+func newValidator(s *schema) *validator {
+	return &validator{root: s}
+}
+
+// token validates the token, and returns an error on exceeding the limits.
+func (v *validator) token(token json.Token) error {
+	for _, c := range v.captures {
+		c.tokens = append(c.tokens, token)
+		if c.size += tokenSize(token); c.size > maxCaptureSize {
+			v.err = errors.Join(append(v.errs,
+				fmt.Errorf("%s: %w", v.path(c.depth), ErrValidationCaptureLimit))...)
+			return v.err
+		}
+	}
+	v.next(token)
+	return v.err
+}
+
+func (v *validator) next(token json.Token) {
+	n := len(v.frames)
+	if n == 0 {
+		v.value([]*schema{v.root}, token)
+		return
+	}
+	switch f := &v.frames[n-1]; {
+	case token == json.Delim('}') || token == json.Delim(']'):
+		v.end()
+	case f.expectKey:
+		v.key(f, token.(string))
+	case f.object:
+		v.value(f.next, token)
+	default:
+		var schemas []*schema
+		for _, s := range f.schemas {
+			if f.index < len(s.prefixItems) {
+				schemas = append(schemas, s.prefixItems[f.index])
+			} else if s.items != nil {
+				schemas = append(schemas, s.items)
+			}
+		}
+		v.value(schemas, token)
+	}
+}
+
+func (v *validator) key(f *validationFrame, key string) {
+	f.key, f.expectKey, f.next = key, false, f.next[:0]
+	f.index++
+	if f.keys != nil {
+		f.keys[key] = true
+	}
+	for _, s := range f.schemas {
+		t, matched := s.properties[key]
+		if matched {
+			f.next = append(f.next, t)
+		}
+		for _, p := range s.patternProperties {
+			if p.pattern.MatchString(key) {
+				f.next, matched = append(f.next, p.schema), true
+			}
+		}
+		if t := s.additionalProperties; !matched && t != nil {
+			if t.boolean != nil && !*t.boolean {
+				v.errorf(len(v.frames), "additional property is not allowed")
+			} else {
+				f.next = append(f.next, t)
+			}
+		}
+	}
+}
+
+func (v *validator) value(schemas []*schema, token json.Token) {
+	depth := len(v.frames)
+	var applied []*schema
+	for _, s := range schemas {
+		for s := range s.conjuncts {
+			if !slices.Contains(applied, s) {
+				applied = append(applied, s)
+			}
+		}
+	}
+	for _, s := range applied {
+		if s.boolean != nil {
+			if !*s.boolean {
+				v.errorf(depth, "value is not allowed")
+			}
+			continue
+		}
+		if len(s.types) > 0 {
+			v.validateType(s, depth, token)
+		}
+		if s.enum != nil || s.hasConst || len(s.anyOf) > 0 || len(s.oneOf) > 0 || s.not != nil {
+			v.captures = append(v.captures,
+				&validationCapture{s, depth, []json.Token{token}, tokenSize(token)})
+		}
+		switch token := token.(type) {
+		case string:
+			v.validateString(s, depth, token)
+		case json.Number:
+			v.validateNumber(s, depth, token)
+		}
+	}
+	switch token {
+	case json.Delim('{'):
+		f := validationFrame{schemas: applied, object: true, expectKey: true}
+		for _, s := range applied {
+			if len(s.required) > 0 {
+				f.keys = map[string]bool{}
+				break
+			}
+		}
+		v.frames = append(v.frames, f)
+	case json.Delim('['):
+		v.frames = append(v.frames, validationFrame{schemas: applied})
+	default:
+		v.complete()
+	}
+}
+
+func (v *validator) end() {
+	f := v.frames[len(v.frames)-1]
+	v.frames = v.frames[:len(v.frames)-1]
+	depth := len(v.frames)
+	for _, s := range f.schemas {
+		if f.object {
+			for _, key := range s.required {
+				if !f.keys[key] {
+					v.errorf(depth, "missing required property %q", key)
+				}
+			}
+			if f.index < s.minProperties {
+				v.errorf(depth, "object size %d is less than minProperties %d",
+					f.index, s.minProperties)
+			}
+			if s.maxProperties >= 0 && f.index > s.maxProperties {
+				v.errorf(depth, "object size %d is greater than maxProperties %d",
+					f.index, s.maxProperties)
+			}
+		} else {
+			if f.index < s.minItems {
+				v.errorf(depth, "array length %d is less than minItems %d",
+					f.index, s.minItems)
+			}
+			if s.maxItems >= 0 && f.index > s.maxItems {
+				v.errorf(depth, "array length %d is greater than maxItems %d",
+					f.index, s.maxItems)
+			}
+		}
+	}
+	v.complete()
+}
+
+// complete evaluates the captures of the completed value, and moves to the
+// next entry of the parent container.
+func (v *validator) complete() {
+	depth := len(v.frames)
+	i := len(v.captures)
+	for i > 0 && v.captures[i-1].depth == depth {
+		i--
+	}
+	for _, c := range v.captures[i:] {
+		v.evaluate(c)
+	}
+	v.captures = v.captures[:i]
+	if depth > 0 {
+		if f := &v.frames[depth-1]; f.object {
+			f.expectKey = true
+		} else {
+			f.index++
+		}
+	}
+}
+
+func (v *validator) evaluate(c *validationCapture) {
+	s := c.schema
+	if s.enum != nil || s.hasConst {
+		value := decodeTokens(c.tokens)
+		if s.enum != nil && !slices.ContainsFunc(s.enum, func(e any) bool {
+			return equalJSON(value, e)
+		}) {
+			v.errorf(c.depth, "value must be one of the enum values")
+		}
+		if s.hasConst && !equalJSON(value, s.constant) {
+			v.errorf(c.depth, "value must be equal to the const value")
+		}
+	}
+	valid := func(s *schema) bool {
+		w := newValidator(s)
+		for _, token := range c.tokens {
+			w.token(token)
+		}
+		return len(w.errs) == 0
+	}
+	if len(s.anyOf) > 0 && !slices.ContainsFunc(s.anyOf, valid) {
+		v.errorf(c.depth, "value does not match any schema of anyOf")
+	}
+	if len(s.oneOf) > 0 {
+		var count int
+		for _, s := range s.oneOf {
+			if valid(s) {
+				count++
+			}
+		}
+		if count == 0 {
+			v.errorf(c.depth, "value does not match any schema of oneOf")
+		} else if count > 1 {
+			v.errorf(c.depth, "value matches %d schemas of oneOf", count)
+		}
+	}
+	if s.not != nil && valid(s.not) {
+		v.errorf(c.depth, "value must not match the schema of not")
+	}
+}
+
+func (v *validator) validateType(s *schema, depth int, token json.Token) {
+	var typ string
+	switch token := token.(type) {
+	case json.Delim:
+		if token == '{' {
+			typ = "object"
+		} else {
+			typ = "array"
+		}
+	case string:
+		typ = "string"
+	case json.Number:
+		typ = "number"
+		if slices.Contains(s.types, "integer") {
+			if r := v.parseNumber(depth, token); r == nil {
+				return
+			} else if r.IsInt() {
+				typ = "integer"
+			}
+		}
+	case bool:
+		typ = "boolean"
+	default:
+		typ = "null"
+	}
+	for _, t := range s.types {
+		if t == typ || t == "number" && typ == "integer" {
+			return
+		}
+	}
+	v.errorf(depth, "expected %s but got %s", strings.Join(s.types, " or "), typ)
+}
+
+func (v *validator) validateString(s *schema, depth int, str string) {
+	if s.minLength > 0 || s.maxLength >= 0 {
+		if l := utf8.RuneCountInString(str); l < s.minLength {
+			v.errorf(depth, "string length %d is less than minLength %d", l, s.minLength)
+		} else if s.maxLength >= 0 && l > s.maxLength {
+			v.errorf(depth, "string length %d is greater than maxLength %d", l, s.maxLength)
+		}
+	}
+	if s.pattern != nil && !s.pattern.MatchString(str) {
+		v.errorf(depth, "string does not match pattern %q", s.pattern)
+	}
+}
+
+func (v *validator) validateNumber(s *schema, depth int, n json.Number) {
+	if s.minimum == "" && s.maximum == "" && s.exclusiveMinimum == "" &&
+		s.exclusiveMaximum == "" && s.multipleOf == "" {
+		return
+	}
+	r := v.parseNumber(depth, n)
+	if r == nil {
+		return
+	}
+	compare := func(m json.Number) int {
+		x, _ := new(big.Rat).SetString(m.String())
+		return r.Cmp(x)
+	}
+	if s.minimum != "" && compare(s.minimum) < 0 {
+		v.errorf(depth, "%s is less than minimum %s", n, s.minimum)
+	}
+	if s.maximum != "" && compare(s.maximum) > 0 {
+		v.errorf(depth, "%s is greater than maximum %s", n, s.maximum)
+	}
+	if s.exclusiveMinimum != "" && compare(s.exclusiveMinimum) <= 0 {
+		v.errorf(depth, "%s is less than or equal to exclusiveMinimum %s", n, s.exclusiveMinimum)
+	}
+	if s.exclusiveMaximum != "" && compare(s.exclusiveMaximum) >= 0 {
+		v.errorf(depth, "%s is greater than or equal to exclusiveMaximum %s", n, s.exclusiveMaximum)
+	}
+	if s.multipleOf != "" {
+		if m, _ := new(big.Rat).SetString(s.multipleOf.String()); m.Sign() > 0 &&
+			!new(big.Rat).Quo(r, m).IsInt() {
+			v.errorf(depth, "%s is not a multiple of %s", n, s.multipleOf)
+		}
+	}
+}
+
+func (v *validator) parseNumber(depth int, n json.Number) *big.Rat {
+	r := parseRat(n)
+	if r == nil {
+		v.errorf(depth, "%s is out of range for validation", n)
+	}
+	return r
+}
+
+// parseRat parses the number as a rational number. This function returns nil
+// for the number with a large exponent, instead of allocating a huge integer.
+func parseRat(n json.Number) *big.Rat {
+	if i := strings.IndexAny(string(n), "eE"); i >= 0 {
+		if exp, err := strconv.Atoi(string(n[i+1:])); err != nil || exp < -1000 || exp > 1000 {
+			return nil
+		}
+	}
+	r, _ := new(big.Rat).SetString(n.String())
+	return r
+}
+
+func (v *validator) errorf(depth int, format string, args ...any) {
+	v.errs = append(v.errs, &ValidationError{
+		Path: v.path(depth), Message: fmt.Sprintf(format, args...),
+	})
+	if len(v.errs) == maxValidationErrors {
+		v.err = errors.Join(append(v.errs, ErrValidationErrorLimit)...)
+	}
+}
+
+// path returns the JSONPath of the value at the depth.
+func (v *validator) path(depth int) string {
+	var sb strings.Builder
+	sb.WriteByte('$')
+	for _, f := range v.frames[:depth] {
+		if !f.object {
+			sb.WriteByte('[')
+			sb.WriteString(strconv.Itoa(f.index))
+			sb.WriteByte(']')
+		} else if isIdentifier(f.key) {
+			sb.WriteByte('.')
+			sb.WriteString(f.key)
+		} else {
+			sb.WriteByte('[')
+			sb.WriteString(strconv.Quote(f.key))
+			sb.WriteByte(']')
+		}
+	}
+	return sb.String()
+}
+
+func isIdentifier(s string) bool {
+	for i, c := range s {
+		if !(c == '_' || 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' ||
+			i > 0 && '0' <= c && c <= '9') {
+			return false
+		}
+	}
+	return s != ""
+}
+
+func tokenSize(token json.Token) int {
+	switch token := token.(type) {
+	case string:
+		return len(token)
+	case json.Number:
+		return len(token)
+	default:
+		return 1
+	}
+}
+
+// decodeTokens decodes the tokens of a value to a value compared with the enum
+// and const values in the schema.
+func decodeTokens(tokens []json.Token) any {
+	v, _ := decodeTokensInternal(tokens)
+	return v
+}
+
+func decodeTokensInternal(tokens []json.Token) (any, []json.Token) {
+	switch token := tokens[0]; token {
+	case json.Delim('{'):
+		m, tokens := map[string]any{}, tokens[1:]
+		for tokens[0] != json.Delim('}') {
+			key := tokens[0].(string)
+			m[key], tokens = decodeTokensInternal(tokens[1:])
+		}
+		return m, tokens[1:]
+	case json.Delim('['):
+		a, tokens := []any{}, tokens[1:]
+		for tokens[0] != json.Delim(']') {
+			var v any
+			v, tokens = decodeTokensInternal(tokens)
+			a = append(a, v)
+		}
+		return a, tokens[1:]
+	default:
+		return token, tokens[1:]
+	}
+}
+
+func equalJSON(x, y any) bool {
+	switch x := x.(type) {
+	case map[string]any:
+		y, ok := y.(map[string]any)
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for k, v := range x {
+			if w, ok := y[k]; !ok || !equalJSON(v, w) {
+				return false
+			}
+		}
+		return true
+	case []any:
+		y, ok := y.([]any)
+		return ok && slices.EqualFunc(x, y, equalJSON)
+	case json.Number:
+		y, ok := y.(json.Number)
+		if !ok {
+			return false
+		} else if x == y {
+			return true
+		}
+		r, s := parseRat(x), parseRat(y)
+		return r != nil && s != nil && r.Cmp(s) == 0
+	default:
+		return x == y
+	}
+}
@@ -0,0 +1,234 @@
+package json2yaml_test
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/itchyny/json2yaml"
+)
+
+func TestConvertSchemaValidation(t *testing.T) {
+	testCases := []struct {
+		name   string
+		schema string
+		src    string
+		errs   []string
+	}{
+		{
+			name:   "type",
+			schema: `{"type": "object", "properties": {"a": {"type": "string"}, "b": {"type": ["integer", "null"]}}}`,
+			src:    `{"a": 1, "b": 1.0} {"a": "x", "b": null} {"b": 1.5} []`,
+			errs: []string{
+				"$.a: expected string but got number",
+				"$.b: expected integer or null but got number",
+				"$: expected object but got array",
+			},
+		},
+		{
+			name: "required and properties",
+			schema: `{"required": ["a", "b"], "minProperties": 2, "maxProperties": 3,
+				"properties": {"a": {}}, "patternProperties": {"^x-": {"type": "string"}},
+				"additionalProperties": false}`,
+			src: `{"a": 1, "x-a": 1, "c": 2, "d e": 3} {"a": 1}`,
+			errs: []string{
+				"$[\"x-a\"]: expected string but got number",
+				"$.c: additional property is not allowed",
+				"$[\"d e\"]: additional property is not allowed",
+				"$: missing required property \"b\"",
+				"$: object size 4 is greater than maxProperties 3",
+				"$: missing required property \"b\"",
+				"$: object size 1 is less than minProperties 2",
+			},
+		},
+		{
+			name: "array",
+			schema: `{"minItems": 2, "maxItems": 3, "prefixItems": [{"type": "string"}],
+				"items": {"type": "number"}}`,
+			src: `["a", 1, 2, "b"] [1] ["a", 1]`,
+			errs: []string{
+				"$[3]: expected number but got string",
+				"$: array length 4 is greater than maxItems 3",
+				"$[0]: expected string but got number",
+				"$: array length 1 is less than minItems 2",
+			},
+		},
+		{
+			name: "string",
+			schema: `{"items": {"type": "string", "minLength": 2, "maxLength": 3,
+				"pattern": "^[a-zé]+$"}}`,
+			src: `["ab", "éé", "a", "abcd", "AB"]`,
+			errs: []string{
+				"$[2]: string length 1 is less than minLength 2",
+				"$[3]: string length 4 is greater than maxLength 3",
+				"$[4]: string does not match pattern \"^[a-zé]+$\"",
+			},
+		},
+		{
+			name: "number",
+			schema: `{"properties": {"a": {"minimum": 1, "maximum": 1e1, "multipleOf": 0.5},
+				"b": {"exclusiveMinimum": 0, "exclusiveMaximum": 1}}}`,
+			src: `{"a": 0, "b": 0} {"a": 10.5, "b": 1} {"a": 1.25, "b": 0.5}
+				{"a": 1.5, "b": 1e-1} {"a": 1e10000}`,
+			errs: []string{
+				"$.a: 0 is less than minimum 1",
+				"$.b: 0 is less than or equal to exclusiveMinimum 0",
+				"$.a: 10.5 is greater than maximum 1e1",
+				"$.b: 1 is greater than or equal to exclusiveMaximum 1",
+				"$.a: 1.25 is not a multiple of 0.5",
+				"$.a: 1e10000 is out of range for validation",
+			},
+		},
+		{
+			name: "enum and const",
+			schema: `{"properties": {"a": {"enum": ["x", 1, [1, {"b": null}]]},
+				"b": {"const": {"c": [true, 1]}}}}`,
+			src: `{"a": "x", "b": {"c": [true, 1.0]}} {"a": 1e0, "b": {"c": [true]}}
+				{"a": [1, {"b": null}]} {"a": [1, {"b": false}], "b": {"c": [true, 1], "d": 1}}`,
+			errs: []string{
+				"$.b: value must be equal to the const value",
+				"$.a: value must be one of the enum values",
+				"$.b: value must be equal to the const value",
+			},
+		},
+		{
+			name: "combinators",
+			schema: `{"items": {"allOf": [{"type": ["string", "number"]}],
+				"anyOf": [{"type": "string"}, {"minimum": 0}],
+				"oneOf": [{"type": "integer"}, {"maximum": 10}],
+				"not": {"const": "x"}}}`,
+			src: `["a", 1, 11, -1, 0.5, "x", null]`,
+			errs: []string{
+				"$[1]: value matches 2 schemas of oneOf",
+				"$[3]: value does not match any schema of anyOf",
+				"$[3]: value matches 2 schemas of oneOf",
+				"$[5]: value must not match the schema of not",
+				"$[6]: expected string or number but got null",
+			},
+		},
+		{
+			name: "nested combinators",
+			schema: `{"properties": {"a": {"oneOf": [
+				{"required": ["b"], "properties": {"b": {"anyOf": [{"type": "string"}, {"enum": [0]}]}}},
+				{"required": ["c"]}]}}}`,
+			src: `{"a": {"b": "x"}} {"a": {"b": 0}} {"a": {"b": 1}} {"a": {"b": 0, "c": 0}} {"a": {"c": 0}}`,
+			errs: []string{
+				"$.a: value does not match any schema of oneOf",
+				"$.a: value matches 2 schemas of oneOf",
+			},
+		},
+		{
+			name: "types and invalid counts",
+			schema: `{"properties": {"a": {"type": "integer"}, "b": {"type": "boolean", "maxLength": -1}},
+				"additionalProperties": {"type": "string", "minLength": 1.5}}`,
+			src: `{"a": 1e10000, "b": true, "c": ""} {"a": 1.5, "b": "xx", "c": 1}`,
+			errs: []string{
+				"$.a: 1e10000 is out of range for validation",
+				"$.a: expected integer but got number",
+				"$.b: expected boolean but got string",
+				"$.c: expected string but got number",
+			},
+		},
+		{
+			name:   "reference",
+			schema: `{"$ref": "#/$defs/node", "$defs": {"node": {"type": "object", "properties": {"children": {"items": {"$ref": "#/$defs/node"}}}}}}`,
+			src:    `{"children": [{"children": []}, {"children": [1]}]}`,
+			errs: []string{
+				"$.children[1].children[0]: expected object but got number",
+			},
+		},
+		{
+			name:   "boolean schema",
+			schema: `{"properties": {"a": false, "b": true}}`,
+			src:    `{"a": 1, "b": 2}`,
+			errs: []string{
+				"$.a: value is not allowed",
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := writeSchemaFiles(t, map[string]string{"schema.json": tc.schema})
+			s, err := json2yaml.LoadSchema(filepath.Join(dir, "schema.json"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var sb, want strings.Builder
+			if err := json2yaml.Convert(&want, strings.NewReader(tc.src)); err != nil {
+				t.Fatal(err)
+			}
+			err = json2yaml.Convert(&sb, strings.NewReader(tc.src), json2yaml.WithSchemaValidation(s))
+			if got, want := diff(sb.String(), want.String()); got != want {
+				t.Fatalf("should write\n  %q\nbut got\n  %q", want, got)
+			}
+			var errs []string
+			if err != nil {
+				for _, err := range err.(interface{ Unwrap() []error }).Unwrap() {
+					var verr *json2yaml.ValidationError
+					if !errors.As(err, &verr) {
+						t.Fatalf("should be a validation error but got %T", err)
+					}
+					errs = append(errs, verr.Error())
+				}
+			}
+			if got, want := strings.Join(errs, "\n"), strings.Join(tc.errs, "\n"); got != want {
+				t.Fatalf("should raise errors\n  %q\nbut got\n  %q", want, got)
+			}
+		})
+	}
+}
+
+func TestConvertSchemaValidationLimits(t *testing.T) {
+	testCases := []struct {
+		name   string
+		schema string
+		src    string
+		want   string
+		err    error
+		count  int
+	}{
+		{
+			name:   "error limit",
+			schema: `{"items": {"type": "string"}}`,
+			src:    `[` + strings.Repeat(`0,`, 200) + `0]`,
+			want:   strings.Repeat("- 0\n", 99) + "- \n",
+			err:    json2yaml.ErrValidationErrorLimit,
+			count:  100,
+		},
+		{
+			name:   "capture size limit",
+			schema: `{"properties": {"a": {"oneOf": [{"type": "array"}, {"type": "object"}]}}, "minProperties": 2}`,
+			src:    `{"a": [` + strings.Repeat(`"`+strings.Repeat("x", 1023)+`",`, 1100) + `""], "b": 0}`,
+			want:   "a:\n" + strings.Repeat("  - "+strings.Repeat("x", 1023)+"\n", 1025) + "  - \n",
+			err:    json2yaml.ErrValidationCaptureLimit,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := writeSchemaFiles(t, map[string]string{"schema.json": tc.schema})
+			s, err := json2yaml.LoadSchema(filepath.Join(dir, "schema.json"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var sb strings.Builder
+			err = json2yaml.Convert(&sb, strings.NewReader(tc.src), json2yaml.WithSchemaValidation(s))
+			if got, want := diff(sb.String(), tc.want); got != want {
+				t.Fatalf("should write\n  %q\nbut got\n  %q", want, got)
+			}
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("should raise an error %q but got error %v", tc.err, err)
+			}
+			var count int
+			for _, err := range err.(interface{ Unwrap() []error }).Unwrap() {
+				var verr *json2yaml.ValidationError
+				if errors.As(err, &verr) {
+					count++
+				}
+			}
+			if count != tc.count {
+				t.Fatalf("should raise %d validation errors but got %d", tc.count, count)
+			}
+		})
+	}
+}
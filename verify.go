@@ -0,0 +1,194 @@
+package json2yaml
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/itchyny/json2yaml/yaml2json"
+)
+
+// VerificationError is returned by [WithVerification] when the output does not
+// parse back to the input. The Path is a JSONPath of the first differing value
+// in the Document (counted from 1), like $.foo[0].bar.
+type VerificationError struct {
+	Document int
+	Path     string
+	Message  string
+}
+
+func (err *VerificationError) Error() string {
+	return fmt.Sprintf("verification failed in document %d: %s: %s",
+		err.Document, err.Path, err.Message)
+}
+
+// verifier holds the tokens of the current document, which are compared with
+// the tokens of the output parsed by the yaml2json package.
+type verifier struct {
+	tokens []json.Token
+	frames []verificationFrame
+}
+
+type verificationFrame struct {
+	object    bool
+	expectKey bool
+	key       string
+	index     int
+}
+
+// verifyToken records the token as it should be read from the output.
+func (c *converter) verifyToken(token json.Token) {
+	switch v := token.(type) {
+	case json.Number:
+		switch c.numberFormat {
+		case CanonicalNumbers:
+			token = json.Number(canonicalNumber(string(v)))
+		case SafeNumbers:
+			if !isSafeInteger(string(v)) {
+				token = string(v)
+			}
+		}
+	case string:
+		if c.invalidUTF8 == PreserveInvalidUTF8 && !utf8.ValidString(v) {
+			// the parsers read the \xNN escape sequences as U+00NN
+			var sb strings.Builder
+			for i := 0; i < len(v); {
+				r, size := utf8.DecodeRuneInString(v[i:])
+				if r == utf8.RuneError && size == 1 {
+					r = rune(v[i])
+				}
+				sb.WriteRune(r)
+				i += size
+			}
+			token = sb.String()
+		}
+	}
+	c.verifier.tokens = append(c.verifier.tokens, token)
+}
+
+// verifyDocument parses the document in the buffer, and compares the tokens
+// with the recorded ones.
+func (c *converter) verifyDocument() error {
+	v := c.verifier
+	defer func() {
+		v.tokens, v.frames = v.tokens[:0], v.frames[:0]
+	}()
+	var buf bytes.Buffer
+	if err := yaml2json.Convert(&buf, bytes.NewReader(c.buf.Bytes())); err != nil {
+		return v.errorf(c.documents, "cannot parse the output: %s", err)
+	}
+	dec := json.NewDecoder(&buf)
+	dec.UseNumber()
+	for _, expected := range v.tokens {
+		// the output of yaml2json is valid JSON, so err is io.EOF if any
+		token, err := dec.Token()
+		if err != nil || !v.next(expected, token) {
+			got := "no value"
+			if err == nil {
+				got = formatToken(token)
+			}
+			return v.errorf(c.documents, "expected %s but got %s", formatToken(expected), got)
+		}
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return v.errorf(c.documents, "got extra value")
+	}
+	return nil
+}
+
+// next compares the tokens, and tracks the path of the next value.
+func (v *verifier) next(expected, token json.Token) bool {
+	if n := len(v.frames); n > 0 {
+		f := &v.frames[n-1]
+		if f.object && f.expectKey {
+			if key, ok := expected.(string); ok {
+				f.key, f.expectKey = key, false
+				return expected == token
+			}
+		}
+	}
+	if expected != token {
+		return false
+	}
+	switch expected {
+	case json.Delim('{'), json.Delim('['):
+		v.frames = append(v.frames, verificationFrame{
+			object: expected == json.Delim('{'), expectKey: true,
+		})
+	case json.Delim('}'), json.Delim(']'):
+		v.frames = v.frames[:len(v.frames)-1]
+		v.advance()
+	default:
+		v.advance()
+	}
+	return true
+}
+
+// advance moves the path to the next value after a value is completed.
+func (v *verifier) advance() {
+	if n := len(v.frames); n > 0 {
+		f := &v.frames[n-1]
+		if f.object {
+			f.expectKey = true
+		} else {
+			f.index++
+		}
+	}
+}
+
+func (v *verifier) errorf(document int, format string, args ...any) error {
+	return &VerificationError{
+		Document: document, Path: v.path(), Message: fmt.Sprintf(format, args...),
+	}
+}
+
+// path returns the JSONPath of the value being compared.
+func (v *verifier) path() string {
+	var sb strings.Builder
+	sb.WriteByte('$')
+	for i, f := range v.frames {
+		if !f.object {
+			sb.WriteByte('[')
+			sb.WriteString(strconv.Itoa(f.index))
+			sb.WriteByte(']')
+		} else if f.expectKey && i == len(v.frames)-1 {
+			break
+		} else if isIdentifier(f.key) {
+			sb.WriteByte('.')
+			sb.WriteString(f.key)
+		} else {
+			sb.WriteByte('[')
+			sb.WriteString(strconv.Quote(f.key))
+			sb.WriteByte(']')
+		}
+	}
+	return sb.String()
+}
+
+func formatToken(token json.Token) string {
+	switch v := token.(type) {
+	case json.Delim:
+		switch v {
+		case '{':
+			return "object"
+		case '[':
+			return "array"
+		case '}':
+			return "end of object"
+		default:
+			return "end of array"
+		}
+	case string:
+		return strconv.Quote(v)
+	case json.Number:
+		return "number " + string(v)
+	case nil:
+		return "null"
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,202 @@
+package json2yaml_test
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/itchyny/json2yaml"
+)
+
+func TestConvertVerification(t *testing.T) {
+	const src = `{"a": ["", "<<", "=", "~", "null", "True", "0664", "0o17", "0x1F", "1e3",
+		"1_000", "+.5", "0X1F", "nULL", "1.", ".5", "-.inf", ".NaN", "- a", "? a", ": a",
+		"a: b", "a #b", "#a", "!a", "&a", "*a",
+		"|", ">", "%a", "@a", "` + "`a" + `", "'a", "\"a", "[a", "{a", "a,b", " a", "a ", "a\n",
+		"a\nb", "\n\na\n\n", "\t", "\u0085", "  ", "\ufeff", "\u007f", "é"],
+		"b": {"": 1, "- a": 2, "a: b": 3, "\n": 4, "null": 5, "1": {"2": [[], {}, [[]]]}},
+		"c": [0, -0, 1.0, 1.50, 1e2, -1E-2, 123456789012345678901234567890, 1e400, 9007199254740993],
+		"d": [true, false, null],
+		"e": "AAECAwQFBgcICQoLDA0ODxAREhMUFRYXGBkaGxwdHh8gISIjJCUmJygpKissLS4vMDEyMzQ1Njc4OTo7PD0+P0BB"}
+		[] {} "" 0 null`
+	testCases := []struct {
+		name string
+		src  string
+		opts []json2yaml.Option
+		err  string
+	}{
+		{
+			name: "default",
+			src:  src,
+		},
+		{
+			name: "options",
+			src:  src,
+			opts: []json2yaml.Option{
+				json2yaml.WithASCII(), json2yaml.WithStringTags(), json2yaml.WithNumberTags(),
+				json2yaml.WithBinaryKeys(regexp.MustCompile(`^e$`)),
+				json2yaml.WithDocumentStart(), json2yaml.WithDocumentEnd(),
+				json2yaml.WithYAMLDirective("1.2"), json2yaml.WithHeaderComment("header\n\ncomment"),
+				json2yaml.WithSourceComment("source"),
+			},
+		},
+		{
+			name: "canonical numbers",
+			src:  src,
+			opts: []json2yaml.Option{json2yaml.WithNumberFormat(json2yaml.CanonicalNumbers)},
+		},
+		{
+			name: "safe numbers",
+			src:  src,
+			opts: []json2yaml.Option{json2yaml.WithNumberFormat(json2yaml.SafeNumbers)},
+		},
+		{
+			name: "core schema profile",
+			src:  src,
+			opts: []json2yaml.Option{json2yaml.WithProfile(json2yaml.CoreSchemaProfile)},
+		},
+		{
+			name: "preserve invalid utf-8",
+			src:  "[\"a\xffb\", \"\xc3\"]",
+			opts: []json2yaml.Option{json2yaml.WithInvalidUTF8(json2yaml.PreserveInvalidUTF8)},
+		},
+		{
+			name: "comments with line breaks",
+			src:  src,
+			opts: []json2yaml.Option{
+				json2yaml.WithHeaderComment("first\rinjected: 2\x00\ufeff"),
+				json2yaml.WithSourceComment("foo\u2028\rbar: 3"),
+			},
+		},
+		{
+			name: "tag directive",
+			src:  `{"a": [1, 100000000000000000000]} {"b": 100000000000000000000}`,
+			opts: []json2yaml.Option{
+				json2yaml.WithTagDirective("!!", "tag:example.com,2000:"),
+				json2yaml.WithNumberTags(),
+			},
+			err: `verification failed in document 1: $.a[1]: expected number 100000000000000000000 but got "100000000000000000000"`,
+		},
+		{
+			name: "tag directive with non-identifier key",
+			src:  `{"a b": 1e400}`,
+			opts: []json2yaml.Option{
+				json2yaml.WithTagDirective("!!", "tag:example.com,2000:"),
+				json2yaml.WithNumberTags(),
+			},
+			err: `verification failed in document 1: $["a b"]: expected number 1e400 but got "1e400"`,
+		},
+		{
+			name: "unsupported version",
+			src:  `1`,
+			opts: []json2yaml.Option{json2yaml.WithYAMLDirective("2.0")},
+			err:  `verification failed in document 1: $: cannot parse the output: line 1, column 10: unsupported YAML version: 2.0`,
+		},
+		{
+			name: "extra document",
+			src:  `1`,
+			opts: []json2yaml.Option{json2yaml.WithYAMLDirective("1.2\n--- 1\n...")},
+			err:  `verification failed in document 1: $: got extra value`,
+		},
+		{
+			name: "extra key",
+			src:  `{}`,
+			opts: []json2yaml.Option{json2yaml.WithYAMLDirective("1.2\n--- {a: 1}\n...")},
+			err:  `verification failed in document 1: $: expected end of object but got "a"`,
+		},
+		{
+			name: "missing key",
+			src:  `{"a": 1}`,
+			opts: []json2yaml.Option{json2yaml.WithYAMLDirective("1.2\n--- {}\n...")},
+			err:  `verification failed in document 1: $.a: expected "a" but got end of object`,
+		},
+		{
+			name: "object for null",
+			src:  `null`,
+			opts: []json2yaml.Option{json2yaml.WithYAMLDirective("1.2\n--- {}\n...")},
+			err:  `verification failed in document 1: $: expected null but got object`,
+		},
+		{
+			name: "boolean for array",
+			src:  `[]`,
+			opts: []json2yaml.Option{json2yaml.WithYAMLDirective("1.2\n--- true\n...")},
+			err:  `verification failed in document 1: $: expected array but got true`,
+		},
+		{
+			name: "missing element",
+			src:  `[1]`,
+			opts: []json2yaml.Option{json2yaml.WithYAMLDirective("1.2\n--- []\n...")},
+			err:  `verification failed in document 1: $[0]: expected number 1 but got end of array`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var sb, want strings.Builder
+			err := json2yaml.Convert(&want, strings.NewReader(tc.src), tc.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = json2yaml.Convert(&sb, strings.NewReader(tc.src),
+				append(tc.opts, json2yaml.WithVerification())...)
+			if tc.err == "" {
+				if err != nil {
+					t.Fatalf("should not raise an error but got: %s", err)
+				}
+				if got, want := diff(sb.String(), want.String()); got != want {
+					t.Fatalf("should write\n  %q\nbut got\n  %q", want, got)
+				}
+			} else {
+				var verr *json2yaml.VerificationError
+				if !errors.As(err, &verr) {
+					t.Fatalf("should raise a verification error but got %v", err)
+				}
+				if got, want := err.Error(), tc.err; got != want {
+					t.Fatalf("should raise an error %q but got %q", want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestConvertVerificationWriteError(t *testing.T) {
+	err := json2yaml.Convert(errWriter{}, strings.NewReader(`[1, 2] 3`),
+		json2yaml.WithVerification())
+	if got, want := err.Error(), fmt.Sprint(len("- 1\n- 2\n")); got != want {
+		t.Fatalf("should raise an error %q but got error %q", want, got)
+	}
+}
+
+func TestConvertVerificationProfile(t *testing.T) {
+	const want = "verification is not supported with the profile other than the default and the core schema"
+	for _, profile := range []json2yaml.Profile{
+		json2yaml.DefaultProfile, json2yaml.CoreSchemaProfile, json2yaml.PyYAMLProfile,
+		json2yaml.GoYAMLV2Profile, json2yaml.GoYAMLV3Profile, json2yaml.SnakeYAMLProfile,
+		json2yaml.PsychProfile,
+	} {
+		opts := []json2yaml.Option{json2yaml.WithProfile(profile), json2yaml.WithVerification()}
+		var sb strings.Builder
+		err := json2yaml.Convert(&sb, strings.NewReader(`{"a": "0o17"}`), opts...)
+		_, rerr := io.ReadAll(json2yaml.NewReader(strings.NewReader(`{"a": "0o17"}`), opts...))
+		eerr := json2yaml.NewEmitter(io.Discard, opts...).StartDocument()
+		if profile == json2yaml.DefaultProfile || profile == json2yaml.CoreSchemaProfile {
+			if err := errors.Join(err, rerr, eerr); err != nil {
+				t.Fatalf("should not raise an error but got: %s", err)
+			}
+			continue
+		}
+		for _, err := range []error{err, rerr, eerr} {
+			if err == nil {
+				t.Fatalf("should raise an error %q but got no error", want)
+			}
+			if got := err.Error(); got != want {
+				t.Fatalf("should raise an error %q but got error %q", want, got)
+			}
+		}
+		if got := sb.String(); got != "" {
+			t.Fatalf("should not write anything but got\n  %q", got)
+		}
+	}
+}
@@ -0,0 +1,1064 @@
+package yaml2json
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+type nodeKind int
+
+const (
+	scalarNode nodeKind = iota
+	sequenceNode
+	mappingNode
+)
+
+type node struct {
+	kind     nodeKind
+	tag      string
+	value    string
+	plain    bool
+	children []*node // sequence entries, or mapping keys and values alternately
+	line     int
+	column   int
+	size     int // number of nodes after expanding aliases, computed on demand
+}
+
+// parser parses YAML streams into the node trees of the documents. The aliases
+// refer to the anchored nodes, so the trees share the nodes.
+type parser struct {
+	src       []byte
+	pos       int
+	line      int
+	lineStart int
+	tags      map[string]string
+	anchors   map[string]*node
+	nodes     int
+}
+
+type parserState struct {
+	pos, line, lineStart int
+}
+
+func newParser(src []byte) *parser {
+	return &parser{src: bytes.TrimPrefix(src, []byte("\uFEFF"))}
+}
+
+// parseDocument parses the next document, and returns nil at the end of the
+// stream.
+func (p *parser) parseDocument() (*node, error) {
+	p.tags = map[string]string{"!": "!", "!!": "tag:yaml.org,2002:"}
+	p.anchors, p.nodes = map[string]*node{}, 0
+	var directives bool
+	for {
+		if err := p.skipToNextContent(); err != nil {
+			return nil, err
+		}
+		if p.eof() {
+			if directives {
+				return nil, p.errorf("expected document start")
+			}
+			return nil, nil
+		}
+		if p.col() == 0 && p.at(0) == '%' {
+			if err := p.parseDirective(); err != nil {
+				return nil, err
+			}
+			directives = true
+			continue
+		}
+		if p.atDocumentMarker("...") && !directives {
+			p.pos += 3
+			continue
+		}
+		break
+	}
+	if p.atDocumentMarker("---") {
+		p.pos += 3
+	} else if directives {
+		return nil, p.errorf("expected document start")
+	}
+	n, err := p.parseBlockNode(-1, false, false)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expectLineEnd(); err != nil {
+		return nil, err
+	}
+	if err := p.skipToNextContent(); err != nil {
+		return nil, err
+	}
+	if p.atDocumentMarker("...") {
+		p.pos += 3
+		if err := p.expectLineEnd(); err != nil {
+			return nil, err
+		}
+	} else if !p.eof() && !p.atDocumentMarker("---") {
+		return nil, p.errorf("expected document end")
+	}
+	return n, nil
+}
+
+func (p *parser) parseDirective() error {
+	p.pos++
+	name := p.scanWord()
+	p.skipSpaces()
+	switch name {
+	case "YAML":
+		version := p.scanWord()
+		if major, _, _ := strings.Cut(version, "."); major != "1" {
+			return p.errorf("unsupported YAML version: %s", version)
+		}
+	case "TAG":
+		handle := p.scanWord()
+		p.skipSpaces()
+		prefix := p.scanWord()
+		if !strings.HasPrefix(handle, "!") || !strings.HasSuffix(handle, "!") || prefix == "" {
+			return p.errorf("invalid TAG directive")
+		}
+		p.tags[handle] = prefix
+	default: // reserved directives are ignored
+		for !p.eof() && !isBreak(p.at(0)) {
+			p.pos++
+		}
+	}
+	return p.expectLineEnd()
+}
+
+// parseBlockNode parses a node in the block context. The node must be indented
+// more than the indent, or a sequence at the indent when seqIndent is true. The
+// compact flag allows block collections on the current line.
+func (p *parser) parseBlockNode(indent int, compact, seqIndent bool) (*node, error) {
+	if err := p.skipToNextContent(); err != nil {
+		return nil, err
+	}
+	lineStart, col := p.atLineStart(), p.col()
+	if p.eof() || p.atDocumentMarker("---") || p.atDocumentMarker("...") ||
+		lineStart && col <= indent && !(seqIndent && col == indent && p.atSequenceEntry()) {
+		return p.newScalar("", true), nil
+	}
+	anchor, tag, err := p.parseProperties()
+	if err != nil {
+		return nil, err
+	}
+	if (anchor != "" || tag != "") && (p.eof() || isBreak(p.at(0)) || p.at(0) == '#') {
+		// the properties of the node on the following lines
+		n, err := p.parseBlockNode(indent, false, seqIndent)
+		if err != nil {
+			return nil, err
+		}
+		return p.applyProperties(n, anchor, tag), nil
+	}
+	return p.parseBlockContent(col, indent, lineStart || compact, anchor, tag)
+}
+
+// parseBlockContent parses the content of a block node at the column, and
+// applies the properties to the node, or to the first key of a block mapping.
+func (p *parser) parseBlockContent(
+	col, indent int, collection bool, anchor, tag string,
+) (n *node, err error) {
+	switch c := p.at(0); {
+	case p.atSequenceEntry():
+		if !collection {
+			return nil, p.errorf("block sequence entries are not allowed in this context")
+		}
+		n, err = p.parseBlockSequence(col)
+	case c == '?' && p.blankz(1):
+		if !collection {
+			return nil, p.errorf("mapping keys are not allowed in this context")
+		}
+		n, err = p.parseBlockMapping(col, nil)
+	case c == '|' || c == '>':
+		n, err = p.parseBlockScalar(indent)
+	case c == '*' && (anchor != "" || tag != ""):
+		return nil, p.errorf("an alias cannot have properties")
+	default:
+		line, plain := p.line, !strings.ContainsRune(`[{"'*`, rune(c))
+		if n, err = p.parseInlineNode(indent, false); err != nil {
+			return nil, err
+		}
+		p.skipSpaces()
+		if p.at(0) == ':' && p.blankz(1) {
+			if !collection || p.line != line {
+				return nil, p.errorf("mapping values are not allowed in this context")
+			}
+			m, err := p.parseBlockMapping(col, p.applyProperties(n, anchor, tag))
+			if err != nil {
+				return nil, err
+			}
+			m.line, m.column = line+1, col+1
+			return m, nil
+		}
+		if plain && p.line == line {
+			p.parsePlainContinuation(n, indent, false)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return p.applyProperties(n, anchor, tag), nil
+}
+
+func (p *parser) parseBlockSequence(col int) (*node, error) {
+	s := p.newNode(sequenceNode)
+	for {
+		p.pos++ // '-'
+		n, err := p.parseBlockNode(col, true, false)
+		if err != nil {
+			return nil, err
+		}
+		s.children = append(s.children, n)
+		if err := p.expectLineEnd(); err != nil {
+			return nil, err
+		}
+		if err := p.skipToNextContent(); err != nil {
+			return nil, err
+		}
+		if p.eof() || p.atDocumentMarker("---") || p.atDocumentMarker("...") || p.col() < col {
+			return s, nil
+		}
+		if p.col() > col {
+			return nil, p.errorf("bad indentation of a sequence entry")
+		}
+		if !p.atSequenceEntry() {
+			return s, nil
+		}
+	}
+}
+
+// parseBlockMapping parses a block mapping at the column. When the first key
+// is given, the position is at the mapping value indicator.
+func (p *parser) parseBlockMapping(col int, key *node) (*node, error) {
+	m := p.newNode(mappingNode)
+	for {
+		var value *node
+		var explicit bool
+		var err error
+		if key == nil {
+			if explicit = p.at(0) == '?' && p.blankz(1); explicit {
+				p.pos++
+				if key, err = p.parseBlockNode(col, true, false); err != nil {
+					return nil, err
+				}
+				if err := p.expectLineEnd(); err != nil {
+					return nil, err
+				}
+				if err := p.skipToNextContent(); err != nil {
+					return nil, err
+				}
+				if p.col() != col || p.at(0) != ':' || !p.blankz(1) {
+					value = p.newScalar("", true)
+				}
+			} else if key, err = p.parseImplicitKey(col); err != nil {
+				return nil, err
+			}
+		}
+		if value == nil {
+			p.pos++ // ':'
+			if value, err = p.parseBlockNode(col, explicit, true); err != nil {
+				return nil, err
+			}
+			if err := p.expectLineEnd(); err != nil {
+				return nil, err
+			}
+		}
+		m.children = append(m.children, key, value)
+		key = nil
+		if err := p.skipToNextContent(); err != nil {
+			return nil, err
+		}
+		if p.eof() || p.atDocumentMarker("---") || p.atDocumentMarker("...") || p.col() < col {
+			return m, nil
+		}
+		if p.col() > col {
+			return nil, p.errorf("bad indentation of a mapping entry")
+		}
+	}
+}
+
+func (p *parser) parseImplicitKey(indent int) (*node, error) {
+	line := p.line
+	anchor, tag, err := p.parseProperties()
+	if err != nil {
+		return nil, err
+	}
+	var key *node
+	if p.at(0) == ':' && p.blankz(1) {
+		key = p.newScalar("", true)
+	} else if c := p.at(0); p.atSequenceEntry() || c == '|' || c == '>' || c == '?' && p.blankz(1) {
+		return nil, p.errorf("expected a mapping key")
+	} else if c == '*' && (anchor != "" || tag != "") {
+		return nil, p.errorf("an alias cannot have properties")
+	} else if key, err = p.parseInlineNode(indent, false); err != nil {
+		return nil, err
+	}
+	p.skipSpaces()
+	if p.at(0) != ':' || !p.blankz(1) || p.line != line {
+		return nil, p.errorf("could not find expected ':'")
+	}
+	return p.applyProperties(key, anchor, tag), nil
+}
+
+// parseInlineNode parses a flow collection, a quoted scalar, an alias or the
+// first line of a plain scalar.
+func (p *parser) parseInlineNode(indent int, flow bool) (*node, error) {
+	switch c := p.at(0); c {
+	case '[':
+		return p.parseFlowSequence()
+	case '{':
+		return p.parseFlowMapping()
+	case '"':
+		return p.parseDoubleQuoted()
+	case '\'':
+		return p.parseSingleQuoted()
+	case '*':
+		return p.parseAlias()
+	case ',', ']', '}', '#', '&', '!', '|', '>', '%', '@', '`':
+		return nil, p.errorf("found character that cannot start any token: %q", c)
+	default:
+		if (c == '-' || c == '?' || c == ':') && (p.blankz(1) || flow && isFlowIndicator(p.at(1))) {
+			return nil, p.errorf("found character that cannot start any token: %q", c)
+		}
+		n := p.newScalar("", true)
+		n.value = p.scanPlainLine(flow)
+		if flow {
+			p.parsePlainContinuation(n, indent, true)
+		}
+		return n, nil
+	}
+}
+
+func (p *parser) scanPlainLine(flow bool) string {
+	start, end := p.pos, p.pos
+	for !p.eof() {
+		c := p.at(0)
+		if isBreak(c) || c == ':' && (p.blankz(1) || flow && isFlowIndicator(p.at(1))) ||
+			flow && isFlowIndicator(c) || c == '#' && p.pos > start && isBlank(p.src[p.pos-1]) {
+			break
+		}
+		p.pos++
+		if !isBlank(c) {
+			end = p.pos
+		}
+	}
+	p.pos = end
+	return string(p.src[start:end])
+}
+
+// parsePlainContinuation parses the continuation lines of the plain scalar,
+// which are indented more than the indent in the block context.
+func (p *parser) parsePlainContinuation(n *node, indent int, flow bool) {
+	var sb strings.Builder
+	sb.WriteString(n.value)
+	for {
+		state := p.save()
+		p.skipSpaces()
+		if p.eof() || !isBreak(p.at(0)) {
+			p.restore(state)
+			break
+		}
+		var breaks int
+		for !p.eof() && isBreak(p.at(0)) {
+			p.skipBreak()
+			p.skipSpaces()
+			breaks++
+		}
+		if p.eof() || p.atDocumentMarker("---") || p.atDocumentMarker("...") ||
+			!flow && p.col() <= indent || p.at(0) == '#' ||
+			p.at(0) == ':' && (p.blankz(1) || flow && isFlowIndicator(p.at(1))) ||
+			flow && isFlowIndicator(p.at(0)) {
+			p.restore(state)
+			break
+		}
+		s := p.scanPlainLine(flow)
+		if breaks == 1 {
+			sb.WriteByte(' ')
+		} else {
+			sb.WriteString(strings.Repeat("\n", breaks-1))
+		}
+		sb.WriteString(s)
+	}
+	n.value = sb.String()
+}
+
+func (p *parser) parseFlowNode() (*node, error) {
+	if err := p.skipFlowSpace(); err != nil {
+		return nil, err
+	}
+	anchor, tag, err := p.parseProperties()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.skipFlowSpace(); err != nil {
+		return nil, err
+	}
+	var n *node
+	if c := p.at(0); p.eof() || c == ',' || c == ']' || c == '}' ||
+		c == ':' && (p.blankz(1) || isFlowIndicator(p.at(1))) {
+		if anchor == "" && tag == "" {
+			return nil, nil
+		}
+		n = p.newScalar("", true)
+	} else if c == '*' && (anchor != "" || tag != "") {
+		return nil, p.errorf("an alias cannot have properties")
+	} else if n, err = p.parseInlineNode(-1, true); err != nil {
+		return nil, err
+	}
+	return p.applyProperties(n, anchor, tag), nil
+}
+
+func (p *parser) parseFlowSequence() (*node, error) {
+	s := p.newNode(sequenceNode)
+	p.pos++ // '['
+	for {
+		if err := p.skipFlowSpace(); err != nil {
+			return nil, err
+		}
+		if p.at(0) == ']' {
+			p.pos++
+			return s, nil
+		}
+		explicit := p.at(0) == '?' && p.blankz(1)
+		if explicit {
+			p.pos++
+		}
+		n, err := p.parseFlowNode()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.skipFlowSpace(); err != nil {
+			return nil, err
+		}
+		if n == nil && !explicit && p.at(0) != ':' {
+			return nil, p.errorf("expected a sequence entry")
+		}
+		if p.at(0) == ':' || explicit {
+			if n, err = p.parseFlowPair(n); err != nil {
+				return nil, err
+			}
+			m := p.newNode(mappingNode)
+			m.children = n.children
+			n = m
+		}
+		s.children = append(s.children, n)
+		if err := p.expectFlowSeparator(']'); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (p *parser) parseFlowMapping() (*node, error) {
+	m := p.newNode(mappingNode)
+	p.pos++ // '{'
+	for {
+		if err := p.skipFlowSpace(); err != nil {
+			return nil, err
+		}
+		if p.at(0) == '}' {
+			p.pos++
+			return m, nil
+		}
+		if p.at(0) == '?' && p.blankz(1) {
+			p.pos++
+		}
+		key, err := p.parseFlowNode()
+		if err != nil {
+			return nil, err
+		}
+		pair, err := p.parseFlowPair(key)
+		if err != nil {
+			return nil, err
+		}
+		m.children = append(m.children, pair.children...)
+		if err := p.expectFlowSeparator('}'); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// parseFlowPair parses the value of the key in the flow context, and returns
+// the pair as a mapping node.
+func (p *parser) parseFlowPair(key *node) (*node, error) {
+	if key == nil {
+		key = p.newScalar("", true)
+	}
+	if err := p.skipFlowSpace(); err != nil {
+		return nil, err
+	}
+	var value *node
+	if p.at(0) == ':' && (p.blankz(1) || isFlowIndicator(p.at(1)) || !key.plain) {
+		p.pos++
+		var err error
+		if value, err = p.parseFlowNode(); err != nil {
+			return nil, err
+		}
+	}
+	if value == nil {
+		value = p.newScalar("", true)
+	}
+	return &node{kind: mappingNode, children: []*node{key, value}}, nil
+}
+
+func (p *parser) expectFlowSeparator(end byte) error {
+	if err := p.skipFlowSpace(); err != nil {
+		return err
+	}
+	switch p.at(0) {
+	case ',':
+		p.pos++
+		return nil
+	case end:
+		return nil
+	}
+	if p.eof() {
+		return p.errorf("unterminated flow collection")
+	}
+	return p.errorf("expected ',' or '%c'", end)
+}
+
+func (p *parser) parseDoubleQuoted() (*node, error) {
+	n := p.newScalar("", false)
+	p.pos++ // '"'
+	var bs []byte
+	var keep int // length of bs not to be trimmed before line breaks
+	for {
+		if p.eof() {
+			return nil, p.errorf("unterminated double-quoted string")
+		}
+		switch c := p.at(0); {
+		case c == '"':
+			p.pos++
+			n.value = string(bs)
+			return n, nil
+		case c == '\\' && isBreak(p.at(1)):
+			p.pos++
+			p.skipBreak()
+			bs = p.foldLines(bs, false)
+			keep = len(bs)
+		case c == '\\':
+			var err error
+			if bs, err = p.parseEscape(bs); err != nil {
+				return nil, err
+			}
+			keep = len(bs)
+		case isBreak(c):
+			bs = trimBlanks(bs, keep)
+			p.skipBreak()
+			bs = p.foldLines(bs, true)
+			keep = len(bs)
+		default:
+			bs = append(bs, c)
+			p.pos++
+		}
+	}
+}
+
+func (p *parser) parseEscape(bs []byte) ([]byte, error) {
+	c := p.at(1)
+	p.pos += 2
+	switch c {
+	case '0':
+		return append(bs, 0), nil
+	case 'a':
+		return append(bs, '\a'), nil
+	case 'b':
+		return append(bs, '\b'), nil
+	case 't', '\t':
+		return append(bs, '\t'), nil
+	case 'n':
+		return append(bs, '\n'), nil
+	case 'v':
+		return append(bs, '\v'), nil
+	case 'f':
+		return append(bs, '\f'), nil
+	case 'r':
+		return append(bs, '\r'), nil
+	case 'e':
+		return append(bs, '\x1B'), nil
+	case ' ', '"', '/', '\\':
+		return append(bs, c), nil
+	case 'N':
+		return utf8.AppendRune(bs, '\u0085'), nil
+	case '_':
+		return utf8.AppendRune(bs, '\u00A0'), nil
+	case 'L':
+		return utf8.AppendRune(bs, '\u2028'), nil
+	case 'P':
+		return utf8.AppendRune(bs, '\u2029'), nil
+	case 'x', 'u', 'U':
+		width := map[byte]int{'x': 2, 'u': 4, 'U': 8}[c]
+		if p.pos+width <= len(p.src) {
+			if r, err := strconv.ParseUint(string(p.src[p.pos:p.pos+width]), 16, 32); err == nil {
+				p.pos += width
+				return utf8.AppendRune(bs, rune(r)), nil
+			}
+		}
+	}
+	p.pos -= 2
+	return nil, p.errorf("found unknown escape character")
+}
+
+func (p *parser) parseSingleQuoted() (*node, error) {
+	n := p.newScalar("", false)
+	p.pos++ // '\''
+	var bs []byte
+	var keep int
+	for {
+		if p.eof() {
+			return nil, p.errorf("unterminated single-quoted string")
+		}
+		switch c := p.at(0); {
+		case c == '\'' && p.at(1) == '\'':
+			bs = append(bs, '\'')
+			p.pos += 2
+		case c == '\'':
+			p.pos++
+			n.value = string(bs)
+			return n, nil
+		case isBreak(c):
+			bs = trimBlanks(bs, keep)
+			p.skipBreak()
+			bs = p.foldLines(bs, true)
+			keep = len(bs)
+		default:
+			bs = append(bs, c)
+			p.pos++
+		}
+	}
+}
+
+// foldLines skips the empty lines and the leading white spaces after a line
+// break, and appends a space or the line breaks of the empty lines.
+func (p *parser) foldLines(bs []byte, space bool) []byte {
+	var breaks int
+	for {
+		p.skipSpaces()
+		if p.eof() || !isBreak(p.at(0)) {
+			break
+		}
+		p.skipBreak()
+		breaks++
+	}
+	if breaks > 0 {
+		return append(bs, strings.Repeat("\n", breaks)...)
+	} else if space {
+		return append(bs, ' ')
+	}
+	return bs
+}
+
+func trimBlanks(bs []byte, keep int) []byte {
+	for len(bs) > keep && isBlank(bs[len(bs)-1]) {
+		bs = bs[:len(bs)-1]
+	}
+	return bs
+}
+
+func (p *parser) parseBlockScalar(indent int) (*node, error) {
+	n := p.newScalar("", false)
+	folded := p.at(0) == '>'
+	p.pos++
+	var chomp byte
+	var increment int
+	for range 2 {
+		switch c := p.at(0); {
+		case (c == '-' || c == '+') && chomp == 0:
+			chomp = c
+			p.pos++
+		case '1' <= c && c <= '9' && increment == 0:
+			increment = int(c - '0')
+			p.pos++
+		}
+	}
+	if err := p.expectLineEnd(); err != nil {
+		return nil, err
+	}
+	contentIndent := -1
+	if increment > 0 {
+		contentIndent = indent + increment
+	} else {
+		// detect the indentation from the first non-empty line
+		state := p.save()
+		for !p.eof() {
+			p.skipBreak()
+			for p.at(0) == ' ' {
+				p.pos++
+			}
+			if !p.eof() && !isBreak(p.at(0)) {
+				if contentIndent = p.col(); contentIndent <= indent {
+					contentIndent = indent + 1
+				}
+				break
+			}
+		}
+		p.restore(state)
+		if contentIndent < 0 {
+			contentIndent = indent + 1
+		}
+	}
+	var lines []string
+	breaks := -1 // line breaks after the last content line, except the header
+	for !p.eof() {
+		state := p.save()
+		p.skipBreak()
+		if breaks++; p.eof() {
+			break
+		}
+		var spaces int
+		for spaces < contentIndent && p.at(spaces) == ' ' {
+			spaces++
+		}
+		if p.pos+spaces >= len(p.src) || isBreak(p.at(spaces)) {
+			p.pos += spaces
+			lines = append(lines, "")
+			continue
+		}
+		if spaces < contentIndent || contentIndent == 0 &&
+			(p.atDocumentMarker("---") || p.atDocumentMarker("...")) {
+			p.restore(state)
+			break
+		}
+		p.pos += spaces
+		start := p.pos
+		for !p.eof() && !isBreak(p.at(0)) {
+			p.pos++
+		}
+		lines = append(lines, string(p.src[start:p.pos]))
+		breaks = 0
+	}
+	last := len(lines)
+	for last > 0 && lines[last-1] == "" {
+		last--
+	}
+	var sb strings.Builder
+	if folded {
+		foldBlockLines(&sb, lines[:last])
+	} else {
+		sb.WriteString(strings.Join(lines[:last], "\n"))
+	}
+	switch chomp {
+	case 0:
+		if last > 0 && breaks > 0 {
+			sb.WriteByte('\n')
+		}
+	case '+':
+		sb.WriteString(strings.Repeat("\n", max(breaks, 0)))
+	}
+	n.value = sb.String()
+	return n, nil
+}
+
+// foldBlockLines folds the lines of the folded block scalar, where the line
+// breaks between the lines of normal text are folded into spaces.
+func foldBlockLines(sb *strings.Builder, lines []string) {
+	var prevNormal, started bool
+	var empties int
+	for _, line := range lines {
+		if line == "" {
+			empties++
+			continue
+		}
+		normal := !isBlank(line[0])
+		if !started {
+			sb.WriteString(strings.Repeat("\n", empties))
+		} else if prevNormal && normal && empties == 0 {
+			sb.WriteByte(' ')
+		} else if prevNormal && normal {
+			sb.WriteString(strings.Repeat("\n", empties))
+		} else {
+			sb.WriteString(strings.Repeat("\n", empties+1))
+		}
+		sb.WriteString(line)
+		prevNormal, started, empties = normal, true, 0
+	}
+}
+
+func (p *parser) parseAlias() (*node, error) {
+	p.pos++ // '*'
+	name := p.scanAnchor()
+	if name == "" {
+		return nil, p.errorf("expected an alias name")
+	}
+	n, ok := p.anchors[name]
+	if !ok {
+		return nil, p.errorf("unknown anchor: %s", name)
+	}
+	return n, nil
+}
+
+// parseProperties parses the anchor and the tag of the node in any order.
+func (p *parser) parseProperties() (anchor, tag string, err error) {
+	for {
+		switch p.at(0) {
+		case '&':
+			if anchor != "" {
+				return "", "", p.errorf("found duplicate anchor")
+			}
+			p.pos++
+			if anchor = p.scanAnchor(); anchor == "" {
+				return "", "", p.errorf("expected an anchor name")
+			}
+		case '!':
+			if tag != "" {
+				return "", "", p.errorf("found duplicate tag")
+			}
+			if tag, err = p.parseTag(); err != nil {
+				return "", "", err
+			}
+		default:
+			return
+		}
+		if !p.eof() && !isBlank(p.at(0)) && !isBreak(p.at(0)) && !isFlowIndicator(p.at(0)) {
+			return "", "", p.errorf("expected a white space after the node property")
+		}
+		p.skipSpaces()
+	}
+}
+
+func (p *parser) parseTag() (string, error) {
+	start := p.pos
+	p.pos++ // '!'
+	if p.at(0) == '<' {
+		i := bytes.IndexByte(p.src[p.pos:], '>')
+		if i < 0 {
+			return "", p.errorf("unterminated verbatim tag")
+		}
+		tag := string(p.src[p.pos+1 : p.pos+i])
+		p.pos += i + 1
+		return tag, nil
+	}
+	for !p.eof() && !isBlank(p.at(0)) && !isBreak(p.at(0)) && !isFlowIndicator(p.at(0)) {
+		p.pos++
+	}
+	tag := string(p.src[start:p.pos])
+	handle, suffix := "!", tag[1:]
+	if i := strings.IndexByte(suffix, '!'); i >= 0 {
+		handle, suffix = tag[:i+2], suffix[i+1:]
+	}
+	if tag == "!" {
+		return tag, nil
+	}
+	prefix, ok := p.tags[handle]
+	if !ok {
+		return "", p.errorf("undefined tag handle: %s", handle)
+	}
+	suffix, err := unescapeURI(suffix)
+	if err != nil {
+		return "", p.errorf("invalid tag: %s", tag)
+	}
+	return prefix + suffix, nil
+}
+
+func unescapeURI(s string) (string, error) {
+	if !strings.Contains(s, "%") {
+		return s, nil
+	}
+	var bs []byte
+	for i := 0; i < len(s); i++ {
+		if s[i] == '%' {
+			if i+3 > len(s) {
+				return "", strconv.ErrSyntax
+			}
+			b, err := strconv.ParseUint(s[i+1:i+3], 16, 8)
+			if err != nil {
+				return "", err
+			}
+			bs = append(bs, byte(b))
+			i += 2
+		} else {
+			bs = append(bs, s[i])
+		}
+	}
+	return string(bs), nil
+}
+
+func (p *parser) applyProperties(n *node, anchor, tag string) *node {
+	if tag != "" {
+		n.tag = tag
+	}
+	if anchor != "" {
+		p.anchors[anchor] = n
+	}
+	return n
+}
+
+func (p *parser) scanAnchor() string {
+	start := p.pos
+	for !p.blankz(0) && !isFlowIndicator(p.at(0)) && !(p.at(0) == ':' && p.blankz(1)) {
+		p.pos++
+	}
+	return string(p.src[start:p.pos])
+}
+
+func (p *parser) scanWord() string {
+	start := p.pos
+	for !p.eof() && !isBlank(p.at(0)) && !isBreak(p.at(0)) {
+		p.pos++
+	}
+	return string(p.src[start:p.pos])
+}
+
+func (p *parser) newNode(kind nodeKind) *node {
+	p.nodes++
+	return &node{kind: kind, line: p.line + 1, column: p.col() + 1}
+}
+
+func (p *parser) newScalar(value string, plain bool) *node {
+	n := p.newNode(scalarNode)
+	n.value, n.plain = value, plain
+	return n
+}
+
+// skipToNextContent skips the white spaces, the line breaks and the comments.
+func (p *parser) skipToNextContent() error {
+	for {
+		p.skipSpaces()
+		if p.at(0) == '#' {
+			for !p.eof() && !isBreak(p.at(0)) {
+				p.pos++
+			}
+		}
+		if p.eof() || !isBreak(p.at(0)) {
+			break
+		}
+		p.skipBreak()
+		for p.at(0) == ' ' {
+			p.pos++
+		}
+		if p.at(0) == '\t' {
+			for p.at(0) == ' ' || p.at(0) == '\t' {
+				p.pos++
+			}
+			if c := p.at(0); !p.eof() && !isBreak(c) && c != '#' {
+				return p.errorf("found a tab character where an indentation space is expected")
+			}
+		}
+	}
+	return nil
+}
+
+func (p *parser) skipFlowSpace() error {
+	for {
+		for !p.eof() && (isBlank(p.at(0)) || isBreak(p.at(0))) {
+			if isBreak(p.at(0)) {
+				p.skipBreak()
+			} else {
+				p.pos++
+			}
+		}
+		if p.at(0) != '#' {
+			break
+		}
+		for !p.eof() && !isBreak(p.at(0)) {
+			p.pos++
+		}
+	}
+	if p.atLineStart() && (p.atDocumentMarker("---") || p.atDocumentMarker("...")) {
+		return p.errorf("unterminated flow collection")
+	}
+	return nil
+}
+
+func (p *parser) skipSpaces() {
+	for isBlank(p.at(0)) {
+		p.pos++
+	}
+}
+
+func (p *parser) skipBreak() {
+	if p.at(0) == '\r' && p.at(1) == '\n' {
+		p.pos++
+	}
+	if isBreak(p.at(0)) {
+		p.pos++
+		p.line++
+		p.lineStart = p.pos
+	}
+}
+
+// expectLineEnd skips the white spaces and the comment, and reports an error
+// if there is any content on the current line.
+func (p *parser) expectLineEnd() error {
+	p.skipSpaces()
+	if c := p.at(0); !p.eof() && !isBreak(c) && !p.atLineStart() {
+		if c != '#' || !isBlank(p.src[p.pos-1]) {
+			return p.errorf("unexpected content after the node")
+		}
+		for !p.eof() && !isBreak(p.at(0)) {
+			p.pos++
+		}
+	}
+	return nil
+}
+
+func (p *parser) atSequenceEntry() bool {
+	return p.at(0) == '-' && p.blankz(1)
+}
+
+// atDocumentMarker reports whether the position is at the document marker at
+// the beginning of a line.
+func (p *parser) atDocumentMarker(marker string) bool {
+	if p.pos != p.lineStart {
+		return false
+	}
+	return bytes.HasPrefix(p.src[p.pos:], []byte(marker)) && p.blankz(3)
+}
+
+// atLineStart reports whether the position is preceded only by spaces on the
+// current line.
+func (p *parser) atLineStart() bool {
+	for _, c := range p.src[p.lineStart:p.pos] {
+		if c != ' ' {
+			return false
+		}
+	}
+	return true
+}
+
+func (p *parser) save() parserState {
+	return parserState{p.pos, p.line, p.lineStart}
+}
+
+func (p *parser) restore(s parserState) {
+	p.pos, p.line, p.lineStart = s.pos, s.line, s.lineStart
+}
+
+func (p *parser) col() int {
+	return p.pos - p.lineStart
+}
+
+func (p *parser) eof() bool {
+	return p.pos >= len(p.src)
+}
+
+func (p *parser) at(i int) byte {
+	if p.pos+i < len(p.src) {
+		return p.src[p.pos+i]
+	}
+	return 0
+}
+
+// blankz reports whether the byte at the offset is a white space, a line
+// break or the end of input.
+func (p *parser) blankz(i int) bool {
+	return p.pos+i >= len(p.src) || isBlank(p.src[p.pos+i]) || isBreak(p.src[p.pos+i])
+}
+
+func (p *parser) errorf(format string, args ...any) error {
+	return &Error{Line: p.line + 1, Column: p.col() + 1, Message: fmt.Sprintf(format, args...)}
+}
+
+func isBlank(c byte) bool {
+	return c == ' ' || c == '\t'
+}
+
+func isBreak(c byte) bool {
+	return c == '\n' || c == '\r'
+}
+
+func isFlowIndicator(c byte) bool {
+	return c == ',' || c == '[' || c == ']' || c == '{' || c == '}'
+}
@@ -0,0 +1,306 @@
+// Package yaml2json implements a converter from YAML to JSON.
+package yaml2json
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math/big"
+	"regexp"
+	"strings"
+)
+
+// Convert reads YAML from r and writes JSON to w. Each document in the YAML
+// stream is written as an indented JSON value. The converter preserves the
+// order of mapping keys and the number representation.
+func Convert(w io.Writer, r io.Reader) error {
+	src, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	c := &converter{w: w, buf: new(bytes.Buffer)}
+	p := newParser(src)
+	for {
+		n, err := p.parseDocument()
+		if err == nil && n != nil {
+			err = c.writeDocument(n, p.nodes)
+		}
+		if ferr := c.flush(); ferr != nil && err == nil {
+			err = ferr
+		}
+		if err != nil || n == nil {
+			return err
+		}
+	}
+}
+
+// Error is returned when the input is not a valid YAML, or cannot be
+// represented in JSON.
+type Error struct {
+	Line, Column int
+	Message      string
+}
+
+func (err *Error) Error() string {
+	return fmt.Sprintf("line %d, column %d: %s", err.Line, err.Column, err.Message)
+}
+
+type converter struct {
+	w   io.Writer
+	buf *bytes.Buffer
+}
+
+func (c *converter) flush() error {
+	_, err := c.w.Write(c.buf.Bytes())
+	c.buf.Reset()
+	return err
+}
+
+const (
+	tagPrefix = "tag:yaml.org,2002:"
+	strTag    = tagPrefix + "str"
+	nullTag   = tagPrefix + "null"
+	boolTag   = tagPrefix + "bool"
+	intTag    = tagPrefix + "int"
+	floatTag  = tagPrefix + "float"
+	binaryTag = tagPrefix + "binary"
+	seqTag    = tagPrefix + "seq"
+	mapTag    = tagPrefix + "map"
+)
+
+// the maximum number of nodes after expanding aliases, to reject the documents
+// like billion laughs; the limit is the larger of these values
+const (
+	maxExpandedNodes      = 1 << 20
+	maxExpandedNodesRatio = 100
+)
+
+func (c *converter) writeDocument(n *node, nodes int) error {
+	if size := n.expandedSize(); size > maxExpandedNodes && size > nodes*maxExpandedNodesRatio {
+		return &Error{n.line, n.column, "document expands too many aliases"}
+	}
+	if err := c.writeNode(n, 0); err != nil {
+		return err
+	}
+	c.buf.WriteByte('\n')
+	return nil
+}
+
+func (n *node) expandedSize() int {
+	if n.size == 0 {
+		size := 1
+		for _, n := range n.children {
+			size += n.expandedSize()
+			if size > maxExpandedNodes*maxExpandedNodesRatio {
+				break
+			}
+		}
+		n.size = size
+	}
+	return n.size
+}
+
+func (c *converter) writeNode(n *node, indent int) error {
+	switch n.kind {
+	case sequenceNode:
+		if n.tag == strTag || n.tag == mapTag {
+			return &Error{n.line, n.column, "unexpected sequence for " + shortTag(n.tag)}
+		}
+		if len(n.children) == 0 {
+			c.buf.WriteString("[]")
+			return nil
+		}
+		c.buf.WriteByte('[')
+		for i, n := range n.children {
+			if i > 0 {
+				c.buf.WriteByte(',')
+			}
+			c.buf.WriteByte('\n')
+			c.writeIndent(indent + 2)
+			if err := c.writeNode(n, indent+2); err != nil {
+				return err
+			}
+		}
+		c.buf.WriteByte('\n')
+		c.writeIndent(indent)
+		c.buf.WriteByte(']')
+	case mappingNode:
+		if n.tag == strTag || n.tag == seqTag {
+			return &Error{n.line, n.column, "unexpected mapping for " + shortTag(n.tag)}
+		}
+		if len(n.children) == 0 {
+			c.buf.WriteString("{}")
+			return nil
+		}
+		c.buf.WriteByte('{')
+		keys := make(map[string]struct{}, len(n.children)/2)
+		for i := 0; i < len(n.children); i += 2 {
+			if i > 0 {
+				c.buf.WriteByte(',')
+			}
+			c.buf.WriteByte('\n')
+			c.writeIndent(indent + 2)
+			key := n.children[i]
+			if key.kind != scalarNode {
+				return &Error{key.line, key.column, "mapping key must be a scalar"}
+			}
+			if _, ok := keys[key.value]; ok {
+				return &Error{key.line, key.column,
+					fmt.Sprintf("found duplicate mapping key %q", key.value)}
+			}
+			keys[key.value] = struct{}{}
+			c.writeString(key.value)
+			c.buf.WriteString(": ")
+			if err := c.writeNode(n.children[i+1], indent+2); err != nil {
+				return err
+			}
+		}
+		c.buf.WriteByte('\n')
+		c.writeIndent(indent)
+		c.buf.WriteByte('}')
+	default:
+		return c.writeScalar(n)
+	}
+	return nil
+}
+
+var (
+	jsonNumberPattern = regexp.MustCompile(`^-?(?:0|[1-9][0-9]*)(?:\.[0-9]+)?(?:[eE][-+]?[0-9]+)?$`)
+	intPattern        = regexp.MustCompile(`^[-+]?[0-9]+$`)
+	floatPattern      = regexp.MustCompile(`^[-+]?(?:\.[0-9]+|[0-9]+(?:\.[0-9]*)?)(?:[eE][-+]?[0-9]+)?$`)
+	basePattern       = regexp.MustCompile(`^0(?:o[0-7]+|x[0-9a-fA-F]+)$`)
+	specialPattern    = regexp.MustCompile(`^(?:[-+]?\.(?:inf|Inf|INF)|\.(?:nan|NaN|NAN))$`)
+)
+
+// writeScalar writes the scalar resolved by the tag, or by the core schema of
+// YAML 1.2 for the untagged plain scalars.
+func (c *converter) writeScalar(n *node) error {
+	tag, v := n.tag, n.value
+	if tag == "" && n.plain {
+		switch {
+		case v == "" || v == "~" || v == "null" || v == "Null" || v == "NULL":
+			tag = nullTag
+		case v == "true" || v == "True" || v == "TRUE" || v == "false" || v == "False" || v == "FALSE":
+			tag = boolTag
+		case intPattern.MatchString(v) || basePattern.MatchString(v):
+			tag = intTag
+		case floatPattern.MatchString(v) || specialPattern.MatchString(v):
+			tag = floatTag
+		}
+	}
+	switch tag {
+	case nullTag:
+		if v != "" && v != "~" && v != "null" && v != "Null" && v != "NULL" {
+			break
+		}
+		c.buf.WriteString("null")
+		return nil
+	case boolTag:
+		switch v {
+		case "true", "True", "TRUE":
+			c.buf.WriteString("true")
+			return nil
+		case "false", "False", "FALSE":
+			c.buf.WriteString("false")
+			return nil
+		}
+	case intTag, floatTag:
+		if basePattern.MatchString(v) {
+			base := 8
+			if v[1] == 'x' {
+				base = 16
+			}
+			i, _ := new(big.Int).SetString(v[2:], base)
+			c.buf.WriteString(i.String())
+			return nil
+		}
+		if intPattern.MatchString(v) || tag == floatTag && floatPattern.MatchString(v) {
+			c.buf.WriteString(normalizeNumber(v))
+			return nil
+		}
+		if tag == floatTag && specialPattern.MatchString(v) {
+			return &Error{n.line, n.column, fmt.Sprintf("cannot represent %s in JSON", v)}
+		}
+	case binaryTag:
+		c.writeString(strings.Join(strings.Fields(v), ""))
+		return nil
+	case seqTag, mapTag:
+		return &Error{n.line, n.column, "unexpected scalar for " + shortTag(tag)}
+	default:
+		c.writeString(v)
+		return nil
+	}
+	return &Error{n.line, n.column, fmt.Sprintf("invalid value for %s: %q", shortTag(tag), v)}
+}
+
+// normalizeNumber converts the number in YAML to the number in JSON, keeping
+// the representation if possible.
+func normalizeNumber(s string) string {
+	if jsonNumberPattern.MatchString(s) {
+		return s
+	}
+	var sign, exp string
+	if s[0] == '-' || s[0] == '+' {
+		sign, s = strings.TrimPrefix(s[:1], "+"), s[1:]
+	}
+	if i := strings.IndexAny(s, "eE"); i >= 0 {
+		s, exp = s[:i], s[i:]
+	}
+	s, frac, dot := strings.Cut(s, ".")
+	if s = strings.TrimLeft(s, "0"); s == "" {
+		s = "0"
+	}
+	if dot {
+		if frac == "" {
+			frac = "0"
+		}
+		s += "." + frac
+	}
+	return sign + s + exp
+}
+
+func shortTag(tag string) string {
+	return "!!" + strings.TrimPrefix(tag, tagPrefix)
+}
+
+func (c *converter) writeString(s string) {
+	const hex = "0123456789abcdef"
+	c.buf.WriteByte('"')
+	start := 0
+	for i := range len(s) {
+		b := s[i]
+		if b >= ' ' && b != '"' && b != '\\' {
+			continue
+		}
+		c.buf.WriteString(s[start:i])
+		switch b {
+		case '"':
+			c.buf.WriteString(`\"`)
+		case '\\':
+			c.buf.WriteString(`\\`)
+		case '\b':
+			c.buf.WriteString(`\b`)
+		case '\f':
+			c.buf.WriteString(`\f`)
+		case '\n':
+			c.buf.WriteString(`\n`)
+		case '\r':
+			c.buf.WriteString(`\r`)
+		case '\t':
+			c.buf.WriteString(`\t`)
+		default:
+			c.buf.Write([]byte{'\\', 'u', '0', '0', hex[b>>4], hex[b&0xF]})
+		}
+		start = i + 1
+	}
+	c.buf.WriteString(s[start:])
+	c.buf.WriteByte('"')
+}
+
+func (c *converter) writeIndent(n int) {
+	const spaces = "                                "
+	for ; n > len(spaces); n -= len(spaces) {
+		c.buf.WriteString(spaces)
+	}
+	c.buf.WriteString(spaces[:n])
+}
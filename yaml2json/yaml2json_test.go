@@ -0,0 +1,640 @@
+package yaml2json_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/itchyny/json2yaml"
+	"github.com/itchyny/json2yaml/yaml2json"
+)
+
+func TestConvert(t *testing.T) {
+	testCases := []struct {
+		name string
+		src  string
+		want string
+		err  string
+	}{
+		{
+			name: "null",
+			src:  "null",
+			want: "null",
+		},
+		{
+			name: "empty",
+			src:  "",
+			want: "",
+		},
+		{
+			name: "comment only",
+			src:  "# comment\n",
+			want: "",
+		},
+		{
+			name: "core schema",
+			src: "[~, null, Null, NULL, true, True, false, FALSE, yes, off, 0, -1, +1, 007, 0o17, 0x1F, " +
+				"1.5, -.5, 1., 1e3, +1.5E-3, 0b1, 1_000, 1:00, 2001-12-14, .inf_]",
+			want: `[null, null, null, null, true, true, false, false, "yes", "off", 0, -1, 1, 7, 15, 31,
+				1.5, -0.5, 1.0, 1e3, 1.5E-3, "0b1", "1_000", "1:00", "2001-12-14", ".inf_"]`,
+		},
+		{
+			name: "large numbers",
+			src:  "[123456789012345678901234567890, 0x123456789abcdef0123, 1.0e+400]",
+			want: "[123456789012345678901234567890, 5373003642731685151011, 1.0e+400]",
+		},
+		{
+			name: "block mapping",
+			src:  "a: 1\nb:\n  c: 2\n  d:\n    e: 3\nf: 4 # comment\n\n# comment\ng:\n",
+			want: `{"a": 1, "b": {"c": 2, "d": {"e": 3}}, "f": 4, "g": null}`,
+		},
+		{
+			name: "block sequence",
+			src:  "- a\n- - b\n  - c\n-\n  - d\n- e: 1\n  f: 2\n-\n- g\n",
+			want: `["a", ["b", "c"], ["d"], {"e": 1, "f": 2}, null, "g"]`,
+		},
+		{
+			name: "sequence in mapping at the same indentation",
+			src:  "a:\n- 1\n- 2\nb:\n  - 3\nc: 4\n",
+			want: `{"a": [1, 2], "b": [3], "c": 4}`,
+		},
+		{
+			name: "quoted keys and values",
+			src:  "\"a: b\": 'c # d'\n'e''f': \"g\\\"h\"\n\"\": ''\n",
+			want: `{"a: b": "c # d", "e'f": "g\"h", "": ""}`,
+		},
+		{
+			name: "explicit keys",
+			src:  "? a\n: 1\n? |-\n  b\n  c\n: 2\n? d\n? - e\n: - f\n",
+			want: `{"a": 1, "b\nc": 2, "d": null, "- e": ["f"]}`,
+			err:  "line 8, column 3: mapping key must be a scalar",
+		},
+		{
+			name: "flow collections",
+			src:  "{a: [1, 2, {b: c}], d: {}, e: [], \"f\":g, h, [i, j: k, ? l : m, \"n\":o]: 0}",
+			want: `{"a": [1, 2, {"b": "c"}], "d": {}, "e": [], "f": "g", "h": null}`,
+			err:  "line 1, column 45: mapping key must be a scalar",
+		},
+		{
+			name: "flow sequence with pairs",
+			src:  "[a: 1, ? b : 2, \"c\":3, d:4, ? , e]\n",
+			want: `[{"a": 1}, {"b": 2}, {"c": 3}, "d:4", {"": null}, "e"]`,
+		},
+		{
+			name: "multi-line flow collections",
+			src:  "a: [1,\n  2, # comment\n  3\n  ]\nb: {\n  c: d e\n   f,\n}\n",
+			want: `{"a": [1, 2, 3], "b": {"c": "d e f"}}`,
+		},
+		{
+			name: "plain multi-line scalars",
+			src:  "a: b\n  c\n\n  d\ne:\n  f\n  g\n- h\n  i\n",
+			err:  "line 8, column 1: expected a mapping key",
+		},
+		{
+			name: "plain multi-line scalars in sequence",
+			src:  "- a\n  b\n\n\n  c\n- d # e\n  # f\n",
+			want: `["a b\n\nc", "d"]`,
+		},
+		{
+			name: "literal block scalars",
+			src: "a: |\n  b\n\n  c\n\n\nd: |-\n  e\n\n\nf: |+\n  g\n\n\nh: |\n   i\n" +
+				"k: |2\n    l\n  m\nn: |\n\n\no: |+\n\np: |\n  \tq\n  r  \n",
+			want: `{"a": "b\n\nc\n", "d": "e", "f": "g\n\n\n", "h": "i\n",
+				"k": "  l\nm\n", "n": "", "o": "\n", "p": "\tq\nr  \n"}`,
+		},
+		{
+			name: "literal block scalar with less indented line",
+			src:  "a: |\n   b\n  c\n",
+			err:  "line 3, column 3: bad indentation of a mapping entry",
+		},
+		{
+			name: "literal block scalars at the end",
+			src:  "a: |-\n  b\n\nc: |+\n  d\n\n",
+			want: `{"a": "b", "c": "d\n\n"}`,
+		},
+		{
+			name: "literal block scalar without final line break",
+			src:  "- |\n  a\n  b",
+			want: `["a\nb"]`,
+		},
+		{
+			name: "folded block scalars",
+			src:  "a: >\n  b\n  c\n\n  d\n    e\n  f\n\n\ng: >-\n\n  h\n  i\n",
+			want: `{"a": "b c\nd\n  e\nf\n", "g": "\nh i"}`,
+		},
+		{
+			name: "empty mapping key",
+			src:  "a: 1\n: 2\n",
+			want: `{"a": 1, "": 2}`,
+		},
+		{
+			name: "plain mapping keys starting with indicators",
+			src:  "a: 0\n?a: 1\n:b: 2\n-c: 3\n",
+			want: `{"a": 0, "?a": 1, ":b": 2, "-c": 3}`,
+		},
+		{
+			name: "flow collections with properties",
+			src:  "[&a , !!str , {? b: 1}]",
+			want: `[null, "", {"b": 1}]`,
+		},
+		{
+			name: "deeply nested flow sequences",
+			src:  strings.Repeat("[", 17) + "1" + strings.Repeat("]", 17),
+			want: strings.Repeat("[", 17) + "1" + strings.Repeat("]", 17),
+		},
+		{
+			name: "literal block scalar without content",
+			src:  "a: |\n\n",
+			want: `{"a": ""}`,
+		},
+		{
+			name: "double-quoted escapes",
+			src:  `"\0\a\b\t\	\n\v\f\r\e\ \"\/\\\N\_\L\P\x41\u00e9\U0001F600"` + "\n",
+			want: `"\u0000\u0007\b\t\t\n\u000b\f\r\u001b \"/\\` + "\u0085\u00a0\u2028\u2029Aé\U0001F600\"",
+		},
+		{
+			name: "double-quoted multi-line",
+			src:  "\"a  \n  b\n\n  c \\\n  d\\  \n  e\\\n\n  f\"\n",
+			want: `"a b\nc d  e\nf"`,
+		},
+		{
+			name: "single-quoted multi-line",
+			src:  "'a  \n  b\n\n  ''c'''\n",
+			want: `"a b\n'c'"`,
+		},
+		{
+			name: "anchors and aliases",
+			src:  "a: &x 1\nb: &y\n  c: *x\nd: *y\ne: &z [*x, *y]\nf: *z\n&k g: *k\n",
+			want: `{"a": 1, "b": {"c": 1}, "d": {"c": 1}, "e": [1, {"c": 1}], "f": [1, {"c": 1}], "g": "g"}`,
+		},
+		{
+			name: "unknown anchor",
+			src:  "a: *x\n",
+			err:  "line 1, column 6: unknown anchor: x",
+		},
+		{
+			name: "billion laughs",
+			src: "a: &a [1, 1, 1, 1, 1, 1, 1, 1, 1, 1]\nb: &b [*a, *a, *a, *a, *a, *a, *a, *a, *a, *a]\n" +
+				"c: &c [*b, *b, *b, *b, *b, *b, *b, *b, *b, *b]\nd: &d [*c, *c, *c, *c, *c, *c, *c, *c, *c, *c]\n" +
+				"e: &e [*d, *d, *d, *d, *d, *d, *d, *d, *d, *d]\nf: &f [*e, *e, *e, *e, *e, *e, *e, *e, *e, *e]\n" +
+				"g: &g [*f, *f, *f, *f, *f, *f, *f, *f, *f, *f]\nh: &h [*g, *g, *g, *g, *g, *g, *g, *g, *g, *g]\n" +
+				"i: &i [*h, *h, *h, *h, *h, *h, *h, *h, *h, *h]\n",
+			err: "line 1, column 1: document expands too many aliases",
+		},
+		{
+			name: "tags",
+			src: "%TAG !e! tag:example.com,2000:\n--- !!map\na: !!str 1\nb: !!int \"0x10\"\nc: !!float 1\n" +
+				"d: !!bool \"true\"\ne: !!null ''\nf: !!binary |\n  aGVs\n  bG8=\ng: ! 1\nh: !e!foo 1\n" +
+				"i: !<tag:yaml.org,2002:str> 1\nj: !!seq [1]\nk: !!str\n",
+			want: `{"a": "1", "b": 16, "c": 1, "d": true, "e": null, "f": "aGVsbG8=", "g": "1", "h": "1",
+				"i": "1", "j": [1], "k": ""}`,
+		},
+		{
+			name: "tag with escaped characters",
+			src:  "- !a%21b 1\n",
+			want: `["1"]`,
+		},
+		{
+			name: "invalid tagged value",
+			src:  "- !!int 1.5\n",
+			err:  "line 1, column 9: invalid value for !!int: \"1.5\"",
+		},
+		{
+			name: "undefined tag handle",
+			src:  "- !e!foo 1\n",
+			err:  "line 1, column 9: undefined tag handle: !e!",
+		},
+		{
+			name: "infinity",
+			src:  "- .inf\n",
+			err:  "line 1, column 3: cannot represent .inf in JSON",
+		},
+		{
+			name: "multiple documents",
+			src:  "a\n---\nb\n...\n---\n--- |\n  c\n...\n# comment\n---\n...\n%YAML 1.2\n---\n- d\n",
+			want: `"a" "b" null "c\n" null ["d"]`,
+		},
+		{
+			name: "reserved directive and document end markers",
+			src:  "%FOO bar\n--- a\n...\n...\nb\n",
+			want: `"a" "b"`,
+		},
+		{
+			name: "document start with comment",
+			src:  "--- # comment\na: 1\n",
+			want: `{"a": 1}`,
+		},
+		{
+			name: "byte order mark and CRLF",
+			src:  "\uFEFFa: 1\r\nb:\r\n  - 2\r\n",
+			want: `{"a": 1, "b": [2]}`,
+		},
+		{
+			name: "bad indentation of mapping",
+			src:  "a:\n    b: 1\n  c: 2\n",
+			err:  "line 3, column 3: bad indentation of a mapping entry",
+		},
+		{
+			name: "bad indentation of sequence",
+			src:  "- [a]\n  - b\n",
+			err:  "line 2, column 3: bad indentation of a sequence entry",
+		},
+		{
+			name: "mapping in mapping value",
+			src:  "a: b: c\n",
+			err:  "line 1, column 5: mapping values are not allowed in this context",
+		},
+		{
+			name: "sequence in mapping value",
+			src:  "a: - b\n",
+			err:  "line 1, column 4: block sequence entries are not allowed in this context",
+		},
+		{
+			name: "unexpected content",
+			src:  "a: \"b\" c\n",
+			err:  "line 1, column 8: unexpected content after the node",
+		},
+		{
+			name: "missing colon",
+			src:  "a: 1\nb\n",
+			err:  "line 2, column 2: could not find expected ':'",
+		},
+		{
+			name: "tab indentation",
+			src:  "a:\n\tb: 1\n",
+			err:  "line 2, column 2: found a tab character where an indentation space is expected",
+		},
+		{
+			name: "unterminated flow sequence",
+			src:  "[a, b\n",
+			err:  "line 2, column 1: unterminated flow collection",
+		},
+		{
+			name: "unterminated double-quoted string",
+			src:  "\"a\n",
+			err:  "line 2, column 1: unterminated double-quoted string",
+		},
+		{
+			name: "unknown escape",
+			src:  "\"\\q\"",
+			err:  "line 1, column 2: found unknown escape character",
+		},
+		{
+			name: "invalid character",
+			src:  "@a\n",
+			err:  "line 1, column 1: found character that cannot start any token: '@'",
+		},
+		{
+			name: "directive without document",
+			src:  "%YAML 1.2\n",
+			err:  "line 2, column 1: expected document start",
+		},
+		{
+			name: "unsupported version",
+			src:  "%YAML 2.0\n---\n",
+			err:  "line 1, column 10: unsupported YAML version: 2.0",
+		},
+		{
+			name: "tab indentation at document start",
+			src:  "\n\ta\n",
+			err:  "line 2, column 2: found a tab character where an indentation space is expected",
+		},
+		{
+			name: "tab indentation after document",
+			src:  "\"a\"\n\tb\n",
+			err:  "line 2, column 2: found a tab character where an indentation space is expected",
+		},
+		{
+			name: "tab indentation in sequence",
+			src:  "- \"a\"\n\tb\n",
+			err:  "line 2, column 2: found a tab character where an indentation space is expected",
+		},
+		{
+			name: "tab indentation after explicit key",
+			src:  "? \"a\"\n\tb\n",
+			err:  "line 2, column 2: found a tab character where an indentation space is expected",
+		},
+		{
+			name: "tab indentation after mapping value",
+			src:  "a: \"b\"\n\tc\n",
+			err:  "line 2, column 2: found a tab character where an indentation space is expected",
+		},
+		{
+			name: "directive without document start",
+			src:  "%YAML 1.2\na\n",
+			err:  "line 2, column 1: expected document start",
+		},
+		{
+			name: "unexpected content after document",
+			src:  "\"a\" b\n",
+			err:  "line 1, column 5: unexpected content after the node",
+		},
+		{
+			name: "duplicate mapping key",
+			src:  "a: 1\nb:\n  c: 2\n\"a\": 3\n",
+			err:  "line 4, column 1: found duplicate mapping key \"a\"",
+		},
+		{
+			name: "duplicate flow mapping key",
+			src:  "[{a: 1, b: 2, a: 3}]",
+			err:  "line 1, column 15: found duplicate mapping key \"a\"",
+		},
+		{
+			name: "unexpected content after document end",
+			src:  "a\n... b\n",
+			err:  "line 2, column 5: unexpected content after the node",
+		},
+		{
+			name: "unexpected content in sequence",
+			src:  "- \"a\" b\n",
+			err:  "line 1, column 7: unexpected content after the node",
+		},
+		{
+			name: "unexpected content after explicit key",
+			src:  "? \"a\" b\n",
+			err:  "line 1, column 7: unexpected content after the node",
+		},
+		{
+			name: "unexpected content after block scalar header",
+			src:  "| a\n",
+			err:  "line 1, column 3: unexpected content after the node",
+		},
+		{
+			name: "missing document end",
+			src:  "\"a\"\nb\n",
+			err:  "line 2, column 1: expected document end",
+		},
+		{
+			name: "invalid TAG directive",
+			src:  "%TAG e tag:x\n---\n",
+			err:  "line 1, column 13: invalid TAG directive",
+		},
+		{
+			name: "invalid character after properties",
+			src:  "&a\n@\n",
+			err:  "line 2, column 1: found character that cannot start any token: '@'",
+		},
+		{
+			name: "invalid character in explicit key",
+			src:  "? @\n",
+			err:  "line 1, column 3: found character that cannot start any token: '@'",
+		},
+		{
+			name: "invalid character in implicit key",
+			src:  "a: 1\n@b: 2\n",
+			err:  "line 2, column 1: found character that cannot start any token: '@'",
+		},
+		{
+			name: "mapping key in mapping value",
+			src:  "a: ? b\n",
+			err:  "line 1, column 4: mapping keys are not allowed in this context",
+		},
+		{
+			name: "alias with properties",
+			src:  "&a *b\n",
+			err:  "line 1, column 4: an alias cannot have properties",
+		},
+		{
+			name: "alias key with properties",
+			src:  "a: 1\n&x *y : 2\n",
+			err:  "line 2, column 4: an alias cannot have properties",
+		},
+		{
+			name: "duplicate anchor",
+			src:  "a: 1\n&x &y b: 2\n",
+			err:  "line 2, column 4: found duplicate anchor",
+		},
+		{
+			name: "missing alias name",
+			src:  "- *\n",
+			err:  "line 1, column 4: expected an alias name",
+		},
+		{
+			name: "missing anchor name",
+			src:  "- & a\n",
+			err:  "line 1, column 4: expected an anchor name",
+		},
+		{
+			name: "duplicate tag",
+			src:  "- !!str !!int a\n",
+			err:  "line 1, column 9: found duplicate tag",
+		},
+		{
+			name: "missing space after tag",
+			src:  "- !<tag:x>a\n",
+			err:  "line 1, column 11: expected a white space after the node property",
+		},
+		{
+			name: "unterminated verbatim tag",
+			src:  "- !<x\n",
+			err:  "line 1, column 4: unterminated verbatim tag",
+		},
+		{
+			name: "incomplete tag escape",
+			src:  "- !%2 1\n",
+			err:  "line 1, column 6: invalid tag: !%2",
+		},
+		{
+			name: "invalid tag escape",
+			src:  "- !%zz 1\n",
+			err:  "line 1, column 7: invalid tag: !%zz",
+		},
+		{
+			name: "invalid character in flow sequence",
+			src:  "[-]",
+			err:  "line 1, column 2: found character that cannot start any token: '-'",
+		},
+		{
+			name: "invalid character in flow mapping key",
+			src:  "{@: 1}",
+			err:  "line 1, column 2: found character that cannot start any token: '@'",
+		},
+		{
+			name: "invalid character in flow mapping value",
+			src:  "{a: @}",
+			err:  "line 1, column 5: found character that cannot start any token: '@'",
+		},
+		{
+			name: "duplicate anchor in flow sequence",
+			src:  "[&a &b c]",
+			err:  "line 1, column 5: found duplicate anchor",
+		},
+		{
+			name: "alias with properties in flow sequence",
+			src:  "[&a *b]",
+			err:  "line 1, column 5: an alias cannot have properties",
+		},
+		{
+			name: "missing flow sequence entry",
+			src:  "[, a]",
+			err:  "line 1, column 2: expected a sequence entry",
+		},
+		{
+			name: "missing flow mapping separator",
+			src:  "{a: \"1\" b}",
+			err:  "line 1, column 9: expected ',' or '}'",
+		},
+		{
+			name: "document start in flow sequence",
+			src:  "[\n---\n",
+			err:  "line 2, column 1: unterminated flow collection",
+		},
+		{
+			name: "document start after flow entry",
+			src:  "[a\n---\n]",
+			err:  "line 2, column 1: unterminated flow collection",
+		},
+		{
+			name: "document start after flow properties",
+			src:  "[&a\n---\n]",
+			err:  "line 2, column 1: unterminated flow collection",
+		},
+		{
+			name: "document start after flow pair key",
+			src:  "[a: \n---\n]",
+			err:  "line 2, column 1: unterminated flow collection",
+		},
+		{
+			name: "document start after flow pair value",
+			src:  "[a: b\n---\n]",
+			err:  "line 2, column 1: unterminated flow collection",
+		},
+		{
+			name: "document start in flow mapping",
+			src:  "{\n---\n}",
+			err:  "line 2, column 1: unterminated flow collection",
+		},
+		{
+			name: "document start after flow mapping key",
+			src:  "{a\n---\n}",
+			err:  "line 2, column 1: unterminated flow collection",
+		},
+		{
+			name: "document start after flow mapping indicator",
+			src:  "{a:\n---\n}",
+			err:  "line 2, column 1: unterminated flow collection",
+		},
+		{
+			name: "unterminated single-quoted string",
+			src:  "'a",
+			err:  "line 1, column 3: unterminated single-quoted string",
+		},
+		{
+			name: "sequence for string tag",
+			src:  "!!str [1]",
+			err:  "line 1, column 7: unexpected sequence for !!str",
+		},
+		{
+			name: "mapping for sequence tag",
+			src:  "!!seq {a: 1}",
+			err:  "line 1, column 7: unexpected mapping for !!seq",
+		},
+		{
+			name: "scalar for sequence tag",
+			src:  "!!seq a",
+			err:  "line 1, column 7: unexpected scalar for !!seq",
+		},
+		{
+			name: "invalid null value",
+			src:  "!!null a",
+			err:  "line 1, column 8: invalid value for !!null: \"a\"",
+		},
+		{
+			name: "infinity in mapping",
+			src:  "a: .inf",
+			err:  "line 1, column 4: cannot represent .inf in JSON",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var sb strings.Builder
+			err := yaml2json.Convert(&sb, strings.NewReader(tc.src))
+			if got, want := sb.String(), indentJSON(t, tc.want); got != want && tc.err == "" {
+				t.Fatalf("should write\n  %q\nbut got\n  %q", want, got)
+			}
+			if tc.err == "" {
+				if err != nil {
+					t.Fatalf("should not raise an error but got: %s", err)
+				}
+			} else {
+				if err == nil {
+					t.Fatalf("should raise an error %q but got no error", tc.err)
+				}
+				if err.Error() != tc.err {
+					t.Fatalf("should raise an error %q but got error %q", tc.err, err)
+				}
+			}
+		})
+	}
+}
+
+type errWriter struct{}
+
+func (errWriter) Write([]byte) (int, error) {
+	return 0, errors.New("write error")
+}
+
+func TestConvertReadWriteError(t *testing.T) {
+	err := yaml2json.Convert(new(strings.Builder), iotest.ErrReader(errors.New("read error")))
+	if got, want := fmt.Sprint(err), "read error"; got != want {
+		t.Fatalf("should raise an error %q but got error %q", want, got)
+	}
+	err = yaml2json.Convert(errWriter{}, strings.NewReader("a: 1\n"))
+	if got, want := fmt.Sprint(err), "write error"; got != want {
+		t.Fatalf("should raise an error %q but got error %q", want, got)
+	}
+}
+
+func indentJSON(t *testing.T, s string) string {
+	t.Helper()
+	var buf bytes.Buffer
+	dec := json.NewDecoder(strings.NewReader(s))
+	for {
+		var v json.RawMessage
+		if err := dec.Decode(&v); err != nil {
+			break
+		}
+		if err := json.Indent(&buf, v, "", "  "); err != nil {
+			t.Fatal(err)
+		}
+		buf.WriteByte('\n')
+	}
+	return buf.String()
+}
+
+func TestConvertRoundTrip(t *testing.T) {
+	const src = `{"a": null, "b": true, "c": false, "d": 0, "e": -1.5e-10, "f": 12345678901234567890,
+		"g": "", "h": "str", "i": "1", "j": "true", "k": "null", "l": "~", "m": "y", "n": "0x1F",
+		"o": "1e3", "p": ".inf", "q": "- a", "r": "a: b", "s": "a #b", "t": "#a", "u": " a", "v": "a ",
+		"w": "a\nb", "x": "a\nb\n", "y": "a\n\n", "z": "\n", "A": " a\nb", "B": "a\tb", "C": "é",
+		"D": "\u0000\u0007\u001b` + "\u007f\u0085\u2028\ufeff" + `", "E": [], "F": {}, "G": [[[]], [{}], {"a": [1]}],
+		"H": "- ", "I": "---", "J": "...", "K": "? a", "L": ": a", "M": "a:", "N": "{a}", "O": "!a",
+		"P": "&a", "Q": "*a", "R": "%a", "S": "@a", "T": "'a'", "U": "\"a\"", "V": "a\r\nb", "W": "|",
+		"a\nb": {"c\nd": "e\nf"}, "": [""], "X": "2001-12-14", "Y": "1:00", "Z": "<<"}
+		[1, "a"] "b" 1 null`
+	optsList := [][]json2yaml.Option{
+		nil,
+		{json2yaml.WithASCII()},
+		{json2yaml.WithStringTags()},
+		{json2yaml.WithNumberTags()},
+		{json2yaml.WithDocumentStart(), json2yaml.WithDocumentEnd()},
+		{json2yaml.WithYAMLDirective("1.2"), json2yaml.WithHeaderComment("comment")},
+	}
+	want := indentJSON(t, src)
+	for _, opts := range optsList {
+		var yaml, sb strings.Builder
+		if err := json2yaml.Convert(&yaml, strings.NewReader(src), opts...); err != nil {
+			t.Fatal(err)
+		}
+		if err := yaml2json.Convert(&sb, strings.NewReader(yaml.String())); err != nil {
+			t.Fatalf("%s\n%s", err, yaml.String())
+		}
+		if got := sb.String(); got != want {
+			t.Fatalf("should write\n  %q\nbut got\n  %q\nfrom\n  %q", want, got, yaml.String())
+		}
+	}
+}